@@ -48,6 +48,23 @@ func Benchmark5Params(B *testing.B) {
 	runRequest(B, router, "GET", "/param/path/to/parameter/john/12345")
 }
 
+// BenchmarkMethodNotAllowed exercises the 405 path with a route registered
+// under every standard HTTP method so the Allow-header collection has to
+// walk every method tree. It guards against reintroducing the extra
+// getValue handleHTTPRequest used to pay for the request's own (missing)
+// method on top of allowedMethods' separate full-tree pass.
+func BenchmarkMethodNotAllowed(B *testing.B) {
+	router := New()
+	router.HandleMethodNotAllowed = true
+	for _, method := range []string{
+		http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodHead, http.MethodOptions,
+	} {
+		router.Handle(method, "/ping", func(c *Context) {})
+	}
+	runRequest(B, router, "TRACE", "/ping")
+}
+
 func BenchmarkOneRouteJSON(B *testing.B) {
 	router := New()
 	data := struct {
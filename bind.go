@@ -0,0 +1,29 @@
+// Copyright 2014 Manu Martinez-Almeida.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import "net/http"
+
+// BoundKey is the context key under which BindMiddleware stores the bound
+// object. Retrieve it in the handler with c.MustGet(BoundKey).
+const BoundKey = "bound"
+
+// BindMiddleware returns a middleware that binds the request into a fresh
+// object produced by newObj, using the same content-type-based binding
+// engine selection as Context.ShouldBind (so query, JSON, XML, form, etc.
+// are all handled automatically). If binding or validation fails, it
+// aborts with 400 and the handler is never called; otherwise the bound
+// object is stored under BoundKey for the handler to retrieve with
+// c.MustGet(gin.BoundKey).
+func BindMiddleware(newObj func() interface{}) HandlerFunc {
+	return func(c *Context) {
+		obj := newObj()
+		if err := c.ShouldBind(obj); err != nil {
+			c.AbortWithError(http.StatusBadRequest, err).SetType(ErrorTypeBind) // nolint: errcheck
+			return
+		}
+		c.Set(BoundKey, obj)
+	}
+}
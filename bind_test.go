@@ -0,0 +1,57 @@
+// Copyright 2014 Manu Martinez-Almeida.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bindMiddlewareTarget struct {
+	Name string `json:"name" binding:"required"`
+}
+
+func TestBindMiddlewareValid(t *testing.T) {
+	router := New()
+	called := false
+	router.POST("/", BindMiddleware(func() interface{} {
+		return &bindMiddlewareTarget{}
+	}), func(c *Context) {
+		called = true
+		obj := c.MustGet(BoundKey).(*bindMiddlewareTarget)
+		assert.Equal(t, "manu", obj.Name)
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"manu"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestBindMiddlewareInvalid(t *testing.T) {
+	router := New()
+	called := false
+	router.POST("/", BindMiddleware(func() interface{} {
+		return &bindMiddlewareTarget{}
+	}), func(c *Context) {
+		called = true
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.False(t, called)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -19,6 +19,7 @@ const (
 	MIMEMSGPACK           = "application/x-msgpack"
 	MIMEMSGPACK2          = "application/msgpack"
 	MIMEYAML              = "application/x-yaml"
+	MIMEYAML2             = "text/yaml"
 )
 
 // Binding describes the interface which needs to be implemented for binding the
@@ -44,6 +45,13 @@ type BindingUri interface {
 	BindUri(map[string][]string, interface{}) error
 }
 
+// BindingHeader adds BindHeader method to Binding. BindHeader is similar
+// with Bind, but it reads a request's headers instead of its body.
+type BindingHeader interface {
+	Name() string
+	BindHeader(map[string][]string, interface{}) error
+}
+
 // StructValidator is the minimal interface which needs to be implemented in
 // order for it to be used as the validator engine for ensuring the correctness
 // of the request. Gin provides a default implementation for this using
@@ -79,6 +87,7 @@ var (
 	MsgPack       = msgpackBinding{}
 	YAML          = yamlBinding{}
 	Uri           = uriBinding{}
+	Header        = headerBinding{}
 )
 
 // Default returns the appropriate Binding instance based on the HTTP method
@@ -99,7 +108,7 @@ func Default(method, contentType string) Binding {
 		return ProtoBuf
 	case MIMEMSGPACK, MIMEMSGPACK2:
 		return MsgPack
-	case MIMEYAML:
+	case MIMEYAML, MIMEYAML2:
 		return YAML
 	default: //case MIMEPOSTForm, MIMEMultipartPOSTForm:
 		return Form
@@ -7,11 +7,14 @@ package binding
 import (
 	"bytes"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"io/ioutil"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -56,15 +59,41 @@ type FooStructForTimeTypeFailLocation struct {
 	TimeFoo time.Time `form:"time_foo" time_format:"2006-01-02" time_location:"/asia/chongqing"`
 }
 
+type FooStructForMultiLayoutTimeType struct {
+	TimeFoo time.Time `form:"time_foo" time_format:"2006-01-02T15:04:05Z07:00,2006-01-02" time_utc:"1"`
+}
+
+type FooStructForUnixTimeType struct {
+	TimeFoo time.Time `form:"time_foo" time_format:"unix" time_utc:"1"`
+}
+
+type FooStructForUnixNanoTimeType struct {
+	TimeFoo time.Time `form:"time_foo" time_format:"unixNano" time_utc:"1"`
+}
+
 type FooStructForMapType struct {
-	// Unknown type: not support map
+	// map[string]interface{} binds from "map_foo[key]=value" but the
+	// interface{} element type itself is still an unsupported element kind
 	MapFoo map[string]interface{} `form:"map_foo"`
 }
 
+type ItemForSliceOfStructType struct {
+	Name  string `form:"name"`
+	Count int    `form:"count"`
+}
+
+type FooStructForSliceOfStructType struct {
+	Items []ItemForSliceOfStructType `form:"items"`
+}
+
 type InvalidNameType struct {
 	TestName string `invalid_name:"test_name"`
 }
 
+type FooStructForNullLiteral struct {
+	Foo *string `form:"foo" null_literal:"null"`
+}
+
 type InvalidNameMapType struct {
 	TestName struct {
 		MapFoo map[string]interface{} `form:"map_foo"`
@@ -87,6 +116,72 @@ type FooStructForStructPointerType struct {
 	}
 }
 
+type FooStructForStringPreprocessorType struct {
+	Name string   `form:"name"`
+	Tags []string `form:"tags" collection_format:"csv"`
+}
+
+type FooStructForCSVSliceType struct {
+	IDs []int `form:"ids" collection_format:"csv"`
+}
+
+type FooStructForCSVSliceStrictType struct {
+	IDs []int `form:"ids" collection_format:"csv_strict"`
+}
+
+type FooStructForNewlineSliceType struct {
+	Lines []string `form:"lines" collection_format:"nl"`
+}
+
+type FooStructForSSVSliceType struct {
+	IDs []int `form:"ids" collection_format:"ssv"`
+}
+
+type FooStructForTextUnmarshalerType struct {
+	IP net.IP `form:"ip"`
+}
+
+type FooStructForTextUnmarshalerSliceType struct {
+	IPs []net.IP `form:"ips"`
+}
+
+type FooStructForTextUnmarshalerPtrType struct {
+	IP *net.IP `form:"ip"`
+}
+
+type FooStructForPipesSliceType struct {
+	IDs []int `form:"ids" collection_format:"pipes"`
+}
+
+type FooStructForArrayType struct {
+	IDs [3]int `form:"ids"`
+}
+
+type FooStructForAliasType struct {
+	CustomerID string `form:"customer_id"`
+}
+
+type FooStructForStringMapType struct {
+	Meta map[string]string `form:"meta"`
+}
+
+type FooStructForIntKeyMapType struct {
+	Counts map[int]string `form:"counts"`
+}
+
+type FooStructForEmptyValuePolicyType struct {
+	IntFoo   int     `form:"int_foo"`
+	UintFoo  uint    `form:"uint_foo"`
+	FloatFoo float64 `form:"float_foo"`
+	BoolFoo  bool    `form:"bool_foo"`
+}
+
+type FooStructForRequiredFieldsType struct {
+	Email string   `form:"email" binding:"required"`
+	Name  string   `form:"name,default=anon" binding:"required"`
+	Tags  []string `form:"tags" binding:"required"`
+}
+
 type FooStructForSliceMapType struct {
 	// Unknown type: not support map
 	SliceMapFoo []map[string]interface{} `form:"slice_map_foo"`
@@ -193,7 +288,7 @@ func TestBindingDefault(t *testing.T) {
 	assert.Equal(t, MsgPack, Default("PUT", MIMEMSGPACK2))
 
 	assert.Equal(t, YAML, Default("POST", MIMEYAML))
-	assert.Equal(t, YAML, Default("PUT", MIMEYAML))
+	assert.Equal(t, YAML, Default("PUT", MIMEYAML2))
 }
 
 func TestBindingJSONNilBody(t *testing.T) {
@@ -278,6 +373,378 @@ func TestBindingFormForTime2(t *testing.T) {
 		"", "")
 }
 
+func TestBindingFormNullLiteral(t *testing.T) {
+	b := Form
+	assert.Equal(t, "form", b.Name())
+
+	obj := FooStructForNullLiteral{}
+	req := requestWithBody("POST", "/", "foo=null")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Nil(t, obj.Foo)
+
+	obj = FooStructForNullLiteral{}
+	req = requestWithBody("POST", "/", "foo=bar")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	err = b.Bind(req, &obj)
+	assert.NoError(t, err)
+	if assert.NotNil(t, obj.Foo) {
+		assert.Equal(t, "bar", *obj.Foo)
+	}
+}
+
+func TestBindingFormCSVSliceMixed(t *testing.T) {
+	b := Form
+	assert.Equal(t, "form", b.Name())
+
+	obj := FooStructForCSVSliceType{}
+	req := requestWithBody("GET", "/?ids=1,2&ids=3", "")
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, obj.IDs)
+}
+
+func TestBindingFormArrayExactFill(t *testing.T) {
+	b := Form
+	assert.Equal(t, "form", b.Name())
+
+	obj := FooStructForArrayType{}
+	req := requestWithBody("GET", "/?ids=1&ids=2&ids=3", "")
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, [3]int{1, 2, 3}, obj.IDs)
+}
+
+func TestBindingFormArrayPartialFillLeavesRestZeroed(t *testing.T) {
+	b := Form
+
+	obj := FooStructForArrayType{}
+	req := requestWithBody("GET", "/?ids=1&ids=2", "")
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, [3]int{1, 2, 0}, obj.IDs)
+}
+
+func TestBindingFormArrayOverflowTruncatesByDefault(t *testing.T) {
+	defer SetArrayOverflowPolicy(ArrayOverflowTruncate)
+
+	b := Form
+	obj := FooStructForArrayType{}
+	req := requestWithBody("GET", "/?ids=1&ids=2&ids=3&ids=4", "")
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, [3]int{1, 2, 3}, obj.IDs)
+}
+
+func TestBindingFormArrayOverflowErrors(t *testing.T) {
+	defer SetArrayOverflowPolicy(ArrayOverflowTruncate)
+	SetArrayOverflowPolicy(ArrayOverflowError)
+
+	b := Form
+	obj := FooStructForArrayType{}
+	req := requestWithBody("GET", "/?ids=1&ids=2&ids=3&ids=4", "")
+	err := b.Bind(req, &obj)
+	assert.Error(t, err)
+}
+
+func TestMapFormWithAliases(t *testing.T) {
+	obj := FooStructForAliasType{}
+	form := map[string][]string{"cust_id": {"42"}}
+	err := MapFormWithAliases(&obj, form, map[string]string{"cust_id": "customer_id"})
+	assert.NoError(t, err)
+	assert.Equal(t, "42", obj.CustomerID)
+}
+
+func TestMapFormWithAliasesUnmappedKeyPassesThrough(t *testing.T) {
+	obj := FooStructForAliasType{}
+	form := map[string][]string{"customer_id": {"7"}}
+	err := MapFormWithAliases(&obj, form, map[string]string{"cust_id": "customer_id"})
+	assert.NoError(t, err)
+	assert.Equal(t, "7", obj.CustomerID)
+}
+
+func TestMapFormWithTagUsesCustomTagName(t *testing.T) {
+	type queryTarget struct {
+		Page int    `query:"page"`
+		Name string `query:"name"`
+	}
+
+	var target queryTarget
+	err := MapFormWithTag(&target, map[string][]string{
+		"page": {"2"},
+		"name": {"foo"},
+	}, "query")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, target.Page)
+	assert.Equal(t, "foo", target.Name)
+}
+
+func TestBindingFormCSVSliceTrailingSeparator(t *testing.T) {
+	b := Form
+	assert.Equal(t, "form", b.Name())
+
+	obj := FooStructForCSVSliceType{}
+	req := requestWithBody("GET", "/?ids=1,2,&ids=3", "")
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, obj.IDs)
+
+	strictObj := FooStructForCSVSliceStrictType{}
+	req = requestWithBody("GET", "/?ids=1,2,&ids=3", "")
+	err = b.Bind(req, &strictObj)
+	assert.Error(t, err)
+}
+
+func TestBindingFormSSVSliceMixed(t *testing.T) {
+	b := Form
+	obj := FooStructForSSVSliceType{}
+	req := requestWithBody("GET", "/?ids=1 2&ids=3", "")
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, obj.IDs)
+}
+
+func TestBindingFormPipesSliceMixed(t *testing.T) {
+	b := Form
+	obj := FooStructForPipesSliceType{}
+	req := requestWithBody("GET", "/?ids=1|2&ids=3", "")
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, obj.IDs)
+}
+
+func TestBindingFormTextUnmarshaler(t *testing.T) {
+	b := Form
+	obj := FooStructForTextUnmarshalerType{}
+	req := requestWithBody("GET", "/?ip=127.0.0.1", "")
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, net.ParseIP("127.0.0.1"), obj.IP)
+}
+
+func TestBindingFormTextUnmarshalerInvalid(t *testing.T) {
+	b := Form
+	obj := FooStructForTextUnmarshalerType{}
+	req := requestWithBody("GET", "/?ip=not-an-ip", "")
+	err := b.Bind(req, &obj)
+	assert.Error(t, err)
+}
+
+func TestBindingFormTextUnmarshalerSlice(t *testing.T) {
+	b := Form
+	obj := FooStructForTextUnmarshalerSliceType{}
+	req := requestWithBody("GET", "/?ips=127.0.0.1&ips=10.0.0.1", "")
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("10.0.0.1")}, obj.IPs)
+}
+
+func TestBindingFormTextUnmarshalerPtr(t *testing.T) {
+	b := Form
+	obj := FooStructForTextUnmarshalerPtrType{}
+	req := requestWithBody("GET", "/?ip=127.0.0.1", "")
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	if assert.NotNil(t, obj.IP) {
+		assert.Equal(t, net.ParseIP("127.0.0.1"), *obj.IP)
+	}
+}
+
+func TestBindingFormNewlineDelimitedSlice(t *testing.T) {
+	b := Form
+	obj := FooStructForNewlineSliceType{}
+	req := requestWithBody("POST", "/", "lines=line1%0D%0Aline2%0Aline3%0A")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"line1", "line2", "line3"}, obj.Lines)
+}
+
+func TestBindingFormMap(t *testing.T) {
+	b := Form
+	obj := FooStructForStringMapType{}
+	req := requestWithBody("POST", "/", "meta[color]=red&meta[size]=large")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"color": "red", "size": "large"}, obj.Meta)
+}
+
+func TestBindingFormMapIntKey(t *testing.T) {
+	b := Form
+	obj := FooStructForIntKeyMapType{}
+	req := requestWithBody("POST", "/", "counts[1]=one&counts[2]=two")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]string{1: "one", 2: "two"}, obj.Counts)
+}
+
+func TestBindingQueryMap(t *testing.T) {
+	b := Query
+	obj := FooStructForStringMapType{}
+	req := requestWithBody("GET", "/?meta[a]=1&meta[b]=2", "")
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, obj.Meta)
+}
+
+func TestBindingFormSliceOfStruct(t *testing.T) {
+	b := Form
+	obj := FooStructForSliceOfStructType{}
+	req := requestWithBody("POST", "/", "items[0].name=a&items[0].count=1&items[1].name=b&items[1].count=2")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, []ItemForSliceOfStructType{{Name: "a", Count: 1}, {Name: "b", Count: 2}}, obj.Items)
+}
+
+func TestBindingFormSliceOfStructSparseIndices(t *testing.T) {
+	b := Form
+	obj := FooStructForSliceOfStructType{}
+	req := requestWithBody("POST", "/", "items[0].name=a&items[3].name=d")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Len(t, obj.Items, 4)
+	assert.Equal(t, "a", obj.Items[0].Name)
+	assert.Equal(t, "", obj.Items[1].Name)
+	assert.Equal(t, "", obj.Items[2].Name)
+	assert.Equal(t, "d", obj.Items[3].Name)
+}
+
+func TestBindingFormSliceOfStructNoMatchingKeysStaysNil(t *testing.T) {
+	b := Form
+	obj := FooStructForSliceOfStructType{}
+	req := requestWithBody("POST", "/", "other=value")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Nil(t, obj.Items)
+}
+
+func TestBindingFormSliceOfStructIndexOverflowRejected(t *testing.T) {
+	b := Form
+	obj := FooStructForSliceOfStructType{}
+	req := requestWithBody("POST", "/", "items[999999999].name=a")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	err := b.Bind(req, &obj)
+	assert.Error(t, err)
+	assert.Nil(t, obj.Items)
+}
+
+func TestEmptyValuePolicyCoerceZero(t *testing.T) {
+	defer SetEmptyValuePolicy(EmptyValueCoerceZero)
+	SetEmptyValuePolicy(EmptyValueCoerceZero)
+
+	b := Form
+	obj := FooStructForEmptyValuePolicyType{}
+	req := requestWithBody("POST", "/", "int_foo=&uint_foo=&float_foo=&bool_foo=")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, FooStructForEmptyValuePolicyType{}, obj)
+}
+
+func TestEmptyValuePolicyError(t *testing.T) {
+	defer SetEmptyValuePolicy(EmptyValueCoerceZero)
+
+	for _, body := range []string{"int_foo=", "uint_foo=", "float_foo=", "bool_foo="} {
+		SetEmptyValuePolicy(EmptyValueError)
+		b := Form
+		obj := FooStructForEmptyValuePolicyType{}
+		req := requestWithBody("POST", "/", body)
+		req.Header.Add("Content-Type", MIMEPOSTForm)
+		err := b.Bind(req, &obj)
+		assert.Error(t, err, body)
+	}
+}
+
+func TestEmptyValuePolicyDefault(t *testing.T) {
+	defer SetEmptyValuePolicy(EmptyValueCoerceZero)
+	SetEmptyValuePolicy(EmptyValueDefault)
+
+	type withDefault struct {
+		IntFoo int `form:"int_foo,default=7"`
+	}
+	b := Form
+	obj := withDefault{}
+	req := requestWithBody("POST", "/", "int_foo=")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, obj.IntFoo)
+}
+
+func TestEmptyValuePolicyCoerceZeroPrefersTagDefault(t *testing.T) {
+	type withDefaults struct {
+		IntFoo   int     `form:"int_foo,default=18"`
+		UintFoo  uint    `form:"uint_foo,default=18"`
+		FloatFoo float64 `form:"float_foo,default=1.5"`
+		BoolFoo  bool    `form:"bool_foo,default=true"`
+	}
+	b := Form
+	obj := withDefaults{}
+	req := requestWithBody("POST", "/", "int_foo=&uint_foo=&float_foo=&bool_foo=")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, 18, obj.IntFoo)
+	assert.Equal(t, uint(18), obj.UintFoo)
+	assert.Equal(t, 1.5, obj.FloatFoo)
+	assert.Equal(t, true, obj.BoolFoo)
+}
+
+func TestBindingFormRequiredFieldsMissing(t *testing.T) {
+	b := Form
+	obj := FooStructForRequiredFieldsType{}
+	req := requestWithBody("POST", "/", "")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	err := b.Bind(req, &obj)
+	if assert.Error(t, err) {
+		missing, ok := err.(MissingRequiredFieldsError)
+		if assert.True(t, ok) {
+			assert.ElementsMatch(t, []string{"email", "tags"}, []string(missing))
+		}
+	}
+}
+
+func TestBindingFormRequiredFieldsPresent(t *testing.T) {
+	b := Form
+	obj := FooStructForRequiredFieldsType{}
+	req := requestWithBody("POST", "/", "email=a@b.com&tags=x")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, "a@b.com", obj.Email)
+	assert.Equal(t, "anon", obj.Name)
+	assert.Equal(t, []string{"x"}, obj.Tags)
+}
+
+func TestBindingFormMapNoMatchingKeysStaysNil(t *testing.T) {
+	b := Form
+	obj := FooStructForStringMapType{}
+	req := requestWithBody("POST", "/", "unrelated=1")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Nil(t, obj.Meta)
+}
+
+func TestBindingFormStringPreprocessor(t *testing.T) {
+	SetStringPreprocessor(strings.ToUpper)
+	defer SetStringPreprocessor(nil)
+
+	b := Form
+	obj := FooStructForStringPreprocessorType{}
+	req := requestWithBody("GET", "/?name=alice&tags=go,web", "")
+	err := b.Bind(req, &obj)
+	assert.NoError(t, err)
+	assert.Equal(t, "ALICE", obj.Name)
+	assert.Equal(t, []string{"GO", "WEB"}, obj.Tags)
+}
+
 func TestBindingFormInvalidName(t *testing.T) {
 	testFormBindingInvalidName(t, "POST",
 		"/", "/",
@@ -287,13 +754,13 @@ func TestBindingFormInvalidName(t *testing.T) {
 func TestBindingFormInvalidName2(t *testing.T) {
 	testFormBindingInvalidName2(t, "POST",
 		"/", "/",
-		"map_foo=bar", "bar2=foo")
+		"map_foo[x]=bar", "bar2=foo")
 }
 
 func TestBindingFormForType(t *testing.T) {
 	testFormBindingForType(t, "POST",
 		"/", "/",
-		"map_foo=", "bar2=1", "Map")
+		"map_foo[x]=bar", "bar2=1", "Map")
 
 	testFormBindingForType(t, "POST",
 		"/", "/",
@@ -454,13 +921,13 @@ func TestBindingQuery2(t *testing.T) {
 
 func TestBindingQueryFail(t *testing.T) {
 	testQueryBindingFail(t, "POST",
-		"/?map_foo=", "/",
+		"/?map_foo[x]=bar", "/",
 		"map_foo=unused", "bar2=foo")
 }
 
 func TestBindingQueryFail2(t *testing.T) {
 	testQueryBindingFail(t, "GET",
-		"/?map_foo=", "/?bar2=foo",
+		"/?map_foo[x]=bar", "/?bar2=foo",
 		"map_foo=unused", "")
 }
 
@@ -484,6 +951,24 @@ func TestBindingXMLFail(t *testing.T) {
 		"<map><foo>bar<foo></map>", "<map><bar>foo</bar></map>")
 }
 
+type xmlBookStruct struct {
+	XMLName xml.Name     `xml:"book"`
+	ISBN    string       `xml:"isbn,attr"`
+	Author  xmlAuthorTag `xml:"author"`
+}
+
+type xmlAuthorTag struct {
+	Name string `xml:"name"`
+}
+
+func TestBindingXMLAttributesAndNestedElements(t *testing.T) {
+	req := requestWithBody("POST", "/", `<book isbn="0141439556"><author><name>Leo Tolstoy</name></author></book>`)
+	var obj xmlBookStruct
+	assert.NoError(t, XML.Bind(req, &obj))
+	assert.Equal(t, "0141439556", obj.ISBN)
+	assert.Equal(t, "Leo Tolstoy", obj.Author.Name)
+}
+
 func TestBindingYAML(t *testing.T) {
 	testBodyBinding(t,
 		YAML, "yaml",
@@ -511,7 +996,7 @@ func createDefaultFormPostRequest() *http.Request {
 }
 
 func createFormPostRequestFail() *http.Request {
-	req, _ := http.NewRequest("POST", "/?map_foo=getfoo", bytes.NewBufferString("map_foo=bar"))
+	req, _ := http.NewRequest("POST", "/?map_foo=getfoo", bytes.NewBufferString("map_foo[x]=bar"))
 	req.Header.Set("Content-Type", MIMEPOSTForm)
 	return req
 }
@@ -537,7 +1022,7 @@ func createFormMultipartRequestFail(t *testing.T) *http.Request {
 	defer mw.Close()
 
 	assert.NoError(t, mw.SetBoundary(boundary))
-	assert.NoError(t, mw.WriteField("map_foo", "bar"))
+	assert.NoError(t, mw.WriteField("map_foo[x]", "bar"))
 	req, _ := http.NewRequest("POST", "/?map_foo=getfoo", body)
 	req.Header.Set("Content-Type", MIMEMultipartPOSTForm+"; boundary="+boundary)
 	return req
@@ -569,6 +1054,20 @@ func TestBindingFormPostFail(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestBindingFormMultipartDefaultMaxMemory(t *testing.T) {
+	req := createFormMultipartRequest(t)
+	assert.Equal(t, int64(defaultMemory), MaxMemoryFromRequest(req))
+}
+
+func TestBindingFormMultipartWithMaxMemory(t *testing.T) {
+	req := createFormMultipartRequest(t)
+	req = WithMaxMemory(req, 8<<20)
+	assert.Equal(t, int64(8<<20), MaxMemoryFromRequest(req))
+
+	var obj FooBarStruct
+	assert.NoError(t, FormMultipart.Bind(req, &obj))
+}
+
 func TestBindingFormMultipart(t *testing.T) {
 	req := createFormMultipartRequest(t)
 	var obj FooBarStruct
@@ -586,6 +1085,56 @@ func TestBindingFormMultipartFail(t *testing.T) {
 	assert.Error(t, err)
 }
 
+type FooStructForFileType struct {
+	Docs []*multipart.FileHeader `form:"docs" max_size:"5B" accept:"application/pdf"`
+}
+
+func createFormMultipartRequestWithDoc(t *testing.T, filename, contentType, content string) *http.Request {
+	boundary := "--testboundary"
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	assert.NoError(t, mw.SetBoundary(boundary))
+	w, err := mw.CreatePart(map[string][]string{
+		"Content-Disposition": {`form-data; name="docs"; filename="` + filename + `"`},
+		"Content-Type":        {contentType},
+	})
+	assert.NoError(t, err)
+	_, err = w.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, mw.Close())
+
+	req, _ := http.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", MIMEMultipartPOSTForm+"; boundary="+boundary)
+	return req
+}
+
+func TestBindingFormMultipartFilesValid(t *testing.T) {
+	req := createFormMultipartRequestWithDoc(t, "a.pdf", "application/pdf", "ok")
+
+	var obj FooStructForFileType
+	err := FormMultipart.Bind(req, &obj)
+	assert.NoError(t, err)
+	if assert.Len(t, obj.Docs, 1) {
+		assert.Equal(t, "a.pdf", obj.Docs[0].Filename)
+	}
+}
+
+func TestBindingFormMultipartFilesTooLarge(t *testing.T) {
+	req := createFormMultipartRequestWithDoc(t, "a.pdf", "application/pdf", "this content is too large")
+
+	var obj FooStructForFileType
+	err := FormMultipart.Bind(req, &obj)
+	assert.Error(t, err)
+}
+
+func TestBindingFormMultipartFilesWrongType(t *testing.T) {
+	req := createFormMultipartRequestWithDoc(t, "a.txt", "text/plain", "ok")
+
+	var obj FooStructForFileType
+	err := FormMultipart.Bind(req, &obj)
+	assert.Error(t, err)
+}
+
 func TestBindingProtoBuf(t *testing.T) {
 	test := &protoexample.Test{
 		Label: proto.String("yes"),
@@ -630,6 +1179,20 @@ func TestBindingMsgPack(t *testing.T) {
 		string(data), string(data[1:]))
 }
 
+func TestBindingMsgPackEmptyBody(t *testing.T) {
+	var obj FooStruct
+	req := requestWithBody("POST", "/", "")
+	err := MsgPack.Bind(req, &obj)
+	assert.Error(t, err)
+}
+
+func TestBindingProtoBufNotAMessage(t *testing.T) {
+	var obj struct{ Foo string }
+	req := requestWithBody("POST", "/", "not-protobuf")
+	err := ProtoBuf.Bind(req, &obj)
+	assert.Error(t, err)
+}
+
 func TestValidationFails(t *testing.T) {
 	var obj FooStruct
 	req := requestWithBody("POST", "/", `{"bar": "foo"}`)
@@ -687,7 +1250,9 @@ func TestUriBinding(t *testing.T) {
 		Name map[string]interface{} `uri:"name"`
 	}
 	var not NotSupportStruct
-	assert.Error(t, b.BindUri(m, &not))
+	mapM := make(map[string][]string)
+	mapM["name[x]"] = []string{"thinkerou"}
+	assert.Error(t, b.BindUri(mapM, &not))
 	assert.Equal(t, map[string]interface{}(nil), not.Name)
 }
 
@@ -713,6 +1278,24 @@ func TestUriInnerBinding(t *testing.T) {
 	assert.Equal(t, tag.S.Age, expectedAge)
 }
 
+func TestHeaderBinding(t *testing.T) {
+	b := Header
+	assert.Equal(t, "header", b.Name())
+
+	type Tag struct {
+		RequestID string   `header:"X-Request-Id"`
+		Accept    []string `header:"Accept"`
+	}
+	var tag Tag
+	header := map[string][]string{
+		"x-request-id": {"abc-123"},
+		"Accept":       {"text/html", "application/json"},
+	}
+	assert.NoError(t, b.BindHeader(header, &tag))
+	assert.Equal(t, "abc-123", tag.RequestID)
+	assert.Equal(t, []string{"text/html", "application/json"}, tag.Accept)
+}
+
 func testFormBinding(t *testing.T, method, path, badPath, body, badBody string) {
 	b := Form
 	assert.Equal(t, "form", b.Name())
@@ -783,6 +1366,54 @@ func TestFormMultipartBindingFail(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestBindingFormMultiLayoutTime(t *testing.T) {
+	b := Form
+
+	obj := FooStructForMultiLayoutTimeType{}
+	req := requestWithBody("POST", "/", "time_foo=2006-01-02T15:04:05Z")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	assert.NoError(t, b.Bind(req, &obj))
+	assert.Equal(t, int64(1136214245), obj.TimeFoo.Unix())
+
+	obj = FooStructForMultiLayoutTimeType{}
+	req = requestWithBody("POST", "/", "time_foo=2006-01-02")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	assert.NoError(t, b.Bind(req, &obj))
+	assert.Equal(t, int64(1136160000), obj.TimeFoo.Unix())
+
+	obj = FooStructForMultiLayoutTimeType{}
+	req = requestWithBody("POST", "/", "time_foo=not-a-time")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	assert.Error(t, b.Bind(req, &obj))
+}
+
+func TestBindingFormUnixTime(t *testing.T) {
+	b := Form
+
+	obj := FooStructForUnixTimeType{}
+	req := requestWithBody("POST", "/", "time_foo=1136214245")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	assert.NoError(t, b.Bind(req, &obj))
+	assert.Equal(t, int64(1136214245), obj.TimeFoo.Unix())
+
+	nanoObj := FooStructForUnixNanoTimeType{}
+	req = requestWithBody("POST", "/", "time_foo=1136214245000000000")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	assert.NoError(t, b.Bind(req, &nanoObj))
+	assert.Equal(t, int64(1136214245), nanoObj.TimeFoo.Unix())
+
+	obj = FooStructForUnixTimeType{}
+	req = requestWithBody("POST", "/", "time_foo=")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	assert.NoError(t, b.Bind(req, &obj))
+	assert.True(t, obj.TimeFoo.IsZero())
+
+	obj = FooStructForUnixTimeType{}
+	req = requestWithBody("POST", "/", "time_foo=not-a-number")
+	req.Header.Add("Content-Type", MIMEPOSTForm)
+	assert.Error(t, b.Bind(req, &obj))
+}
+
 func testFormBindingForTime(t *testing.T, method, path, badPath, body, badBody string) {
 	b := Form
 	assert.Equal(t, "form", b.Name())
@@ -1292,3 +1923,119 @@ func TestCanSet(t *testing.T) {
 	var c CanSetStruct
 	assert.Nil(t, mapForm(&c, nil))
 }
+
+func TestMapFormPartialSkipsFailedField(t *testing.T) {
+	type partialTarget struct {
+		First  string `form:"first"`
+		Second int    `form:"second"`
+		Third  string `form:"third"`
+		Fourth string `form:"fourth"`
+	}
+
+	var target partialTarget
+	err := MapFormPartial(&target, map[string][]string{
+		"first":  {"one"},
+		"second": {"not-a-number"},
+		"third":  {"three"},
+		"fourth": {"four"},
+	})
+
+	assert.Equal(t, "one", target.First)
+	assert.Equal(t, 0, target.Second)
+	assert.Equal(t, "three", target.Third)
+	assert.Equal(t, "four", target.Fourth)
+
+	assert.Error(t, err)
+	mapErrs, ok := err.(MapFormErrors)
+	assert.True(t, ok)
+	if assert.Len(t, mapErrs, 1) {
+		assert.Equal(t, "Second", mapErrs[0].Field)
+	}
+}
+
+func TestMapFormPartialReportsEveryConversionFailure(t *testing.T) {
+	type partialTarget struct {
+		Age    int     `form:"age"`
+		Score  float64 `form:"score"`
+		Active bool    `form:"active"`
+	}
+
+	var target partialTarget
+	err := MapFormPartial(&target, map[string][]string{
+		"age":    {"abc"},
+		"score":  {"xyz"},
+		"active": {"nope"},
+	})
+
+	assert.Error(t, err)
+	mapErrs, ok := err.(MapFormErrors)
+	assert.True(t, ok)
+	assert.Len(t, mapErrs, 3)
+
+	byField := make(map[string]string, len(mapErrs))
+	for _, fe := range mapErrs {
+		byField[fe.Field] = fe.Error()
+	}
+	assert.Equal(t, `field "Age": cannot parse "abc" as int`, byField["Age"])
+	assert.Equal(t, `field "Score": cannot parse "xyz" as float`, byField["Score"])
+	assert.Equal(t, `field "Active": cannot parse "nope" as bool`, byField["Active"])
+}
+
+type EmbeddedPagination struct {
+	Page int `form:"page"`
+	Size int `form:"size"`
+}
+
+type EmbeddedFilter struct {
+	Name string `form:"name"`
+}
+
+func TestMapFormFlattensEmbeddedAnonymousStructs(t *testing.T) {
+	type req struct {
+		EmbeddedPagination
+		EmbeddedFilter
+	}
+
+	var target req
+	assert.NoError(t, mapForm(&target, map[string][]string{
+		"page": {"2"},
+		"size": {"50"},
+		"name": {"foo"},
+	}))
+	assert.Equal(t, 2, target.Page)
+	assert.Equal(t, 50, target.Size)
+	assert.Equal(t, "foo", target.Name)
+}
+
+func TestMapFormFlattensEmbeddedAnonymousStructPointer(t *testing.T) {
+	type req struct {
+		*EmbeddedPagination
+	}
+
+	var target req
+	assert.NoError(t, mapForm(&target, map[string][]string{
+		"page": {"3"},
+		"size": {"60"},
+	}))
+	if assert.NotNil(t, target.EmbeddedPagination) {
+		assert.Equal(t, 3, target.Page)
+		assert.Equal(t, 60, target.Size)
+	}
+}
+
+func TestMapFormPartialAllFieldsValid(t *testing.T) {
+	type partialTarget struct {
+		First  string `form:"first"`
+		Second int    `form:"second"`
+	}
+
+	var target partialTarget
+	err := MapFormPartial(&target, map[string][]string{
+		"first":  {"one"},
+		"second": {"2"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "one", target.First)
+	assert.Equal(t, 2, target.Second)
+}
@@ -32,13 +32,28 @@ func (v *defaultValidator) ValidateStruct(obj interface{}) error {
 	if valueType == reflect.Struct {
 		v.lazyinit() //懒加载
 		if err := v.validate.Struct(obj); err != nil {
-			return err
+			return toValidationErrors(err)
 		}
 	}
-	
+
 	return nil
 }
 
+// toValidationErrors adapts the validator.v8 engine's own error type into
+// binding.ValidationErrors, so callers only ever see gin's FieldError rather
+// than reaching into the underlying engine to read Field/Tag/Value.
+func toValidationErrors(err error) error {
+	v8Errs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+	errs := make(ValidationErrors, 0, len(v8Errs))
+	for _, fe := range v8Errs {
+		errs = append(errs, &FieldError{Field: fe.Field, Tag: fe.Tag, Value: fe.Value})
+	}
+	return errs
+}
+
 // Engine returns the underlying validator engine which powers the default Validator instance. 
 // This is useful if you want to register custom validations or struct level validations. 
 // 
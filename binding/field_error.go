@@ -0,0 +1,40 @@
+// Copyright 2014 Manu Martinez-Almeida.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package binding
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError describes a single struct field that failed validation: which
+// field, which "binding" rule it violated (e.g. "required", "min"), and the
+// value that was rejected, so an API handler can turn it into a per-field
+// error such as {"email": "required"} without reaching into the underlying
+// validator engine's own types.
+type FieldError struct {
+	Field string
+	Tag   string
+	Value interface{}
+}
+
+func (fe *FieldError) Error() string {
+	return fmt.Sprintf("field %q failed on the %q rule", fe.Field, fe.Tag)
+}
+
+// ValidationErrors is every FieldError produced by a single validate() call.
+// The default validator never stops at the first failing field -- it always
+// returns the complete set, so a client can be told about every invalid
+// field at once instead of fixing them one request at a time. It implements
+// error, so callers that only check "err != nil" keep working unchanged.
+type ValidationErrors []*FieldError
+
+func (ve ValidationErrors) Error() string {
+	msgs := make([]string, len(ve))
+	for i, fe := range ve {
+		msgs[i] = fe.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
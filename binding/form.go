@@ -4,10 +4,38 @@
 
 package binding
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+)
 
 const defaultMemory = 32 * 1024 * 1024
 
+// maxMemoryCtxKey is the request-context key WithMaxMemory stores a bind's
+// multipart memory limit under.
+type maxMemoryCtxKey struct{}
+
+// WithMaxMemory returns a shallow copy of req carrying maxMemory as the
+// limit formBinding and formMultipartBinding pass to ParseMultipartForm,
+// overriding defaultMemory for this one request only. gin.Context.ShouldBindWith
+// calls this with Engine.MaxMultipartMemory so a caller configuring the
+// engine-wide limit doesn't also have to reach into this package directly.
+// Unlike a package-level var, carrying the limit on the request itself means
+// two *Engine instances with different MaxMultipartMemory settings binding
+// concurrently can never read or clobber each other's limit.
+func WithMaxMemory(req *http.Request, maxMemory int64) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), maxMemoryCtxKey{}, maxMemory))
+}
+
+// MaxMemoryFromRequest returns the limit installed by WithMaxMemory, or
+// defaultMemory if none was set.
+func MaxMemoryFromRequest(req *http.Request) int64 {
+	if v, ok := req.Context().Value(maxMemoryCtxKey{}).(int64); ok {
+		return v
+	}
+	return defaultMemory
+}
+
 type formBinding struct{}
 type formPostBinding struct{}
 type formMultipartBinding struct{}
@@ -25,7 +53,7 @@ func (formBinding) Bind(req *http.Request, obj interface{}) error {
 	}
 
 	//parse "multipart/form-data" body params, saved both in req.MultipartForm and req.Form .
-	if err := req.ParseMultipartForm(defaultMemory); err != nil {
+	if err := req.ParseMultipartForm(MaxMemoryFromRequest(req)); err != nil {
 		if err != http.ErrNotMultipart {
 			return err
 		}
@@ -59,11 +87,14 @@ func (formMultipartBinding) Name() string {
 
 func (formMultipartBinding) Bind(req *http.Request, obj interface{}) error {
 	//parse "multipart/form-data" body params, saved in r.MultipartForm.
-	if err := req.ParseMultipartForm(defaultMemory); err != nil {
+	if err := req.ParseMultipartForm(MaxMemoryFromRequest(req)); err != nil {
 		return err
 	}
 	if err := mapForm(obj, req.MultipartForm.Value); err != nil {
 		return err
 	}
+	if err := mapFormFiles(obj, req.MultipartForm.File); err != nil {
+		return err
+	}
 	return validate(obj)
 }
@@ -5,33 +5,246 @@
 package binding
 
 import (
+	"encoding"
 	"errors"
+	"fmt"
+	"mime/multipart"
 	"reflect"
 	"strconv"
 	"strings"
 	"time"
 )
 
+var fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+// collectionFormatDelimiters maps a "collection_format" tag value (minus any
+// "_strict" suffix) to the separator it splits a single form value on,
+// following the OpenAPI collectionFormat naming: "csv" (comma), "ssv"
+// (space), "pipes" (pipe).
+var collectionFormatDelimiters = map[string]string{
+	"csv":   ",",
+	"ssv":   " ",
+	"pipes": "|",
+}
+
+// stringPreprocessor is applied to every string value just before it's set
+// into a struct field, for scalar and slice fields alike. Identity by
+// default; override with SetStringPreprocessor to centralize input
+// hygiene such as Unicode normalization or stripping control characters.
+var stringPreprocessor = func(s string) string { return s }
+
+// SetStringPreprocessor installs fn as the global string preprocessor used
+// by mapFormByTag. Passing nil restores the identity default.
+func SetStringPreprocessor(fn func(string) string) {
+	if fn == nil {
+		fn = func(s string) string { return s }
+	}
+	stringPreprocessor = fn
+}
+
+// mapFormFiles populates []*multipart.FileHeader fields tagged with "form"
+// from the uploaded files and enforces the optional max_size/accept tags.
+func mapFormFiles(ptr interface{}, files map[string][]*multipart.FileHeader) error {
+	typ := reflect.TypeOf(ptr).Elem()
+	val := reflect.ValueOf(ptr).Elem()
+
+	for i := 0; i < typ.NumField(); i++ {
+		typeField := typ.Field(i)
+		structField := val.Field(i)
+		if !structField.CanSet() {
+			continue
+		}
+
+		if structField.Kind() != reflect.Slice || structField.Type().Elem() != fileHeaderType {
+			continue
+		}
+
+		fieldName := typeField.Tag.Get("form")
+		if fieldName == "" {
+			fieldName = typeField.Name
+		}
+
+		headers := files[fieldName]
+		if len(headers) == 0 {
+			continue
+		}
+
+		maxSize, err := parseFileSize(typeField.Tag.Get("max_size"))
+		if err != nil {
+			return err
+		}
+		accept := typeField.Tag.Get("accept")
+
+		for _, fh := range headers {
+			if maxSize > 0 && fh.Size > maxSize {
+				return fmt.Errorf("field %q: file %q exceeds max_size %s", fieldName, fh.Filename, typeField.Tag.Get("max_size"))
+			}
+			if accept != "" && fh.Header.Get("Content-Type") != accept {
+				return fmt.Errorf("field %q: file %q has content type %q, expected %q", fieldName, fh.Filename, fh.Header.Get("Content-Type"), accept)
+			}
+		}
+
+		slice := reflect.MakeSlice(structField.Type(), len(headers), len(headers))
+		for i, fh := range headers {
+			slice.Index(i).Set(reflect.ValueOf(fh))
+		}
+		structField.Set(slice)
+	}
+	return nil
+}
+
+// parseFileSize parses human-readable sizes such as "5MB" or "500KB".
+// An empty string means no limit (0, nil).
+func parseFileSize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(upper, "GB"):
+		multiplier = 1 << 30
+		upper = strings.TrimSuffix(upper, "GB")
+	case strings.HasSuffix(upper, "MB"):
+		multiplier = 1 << 20
+		upper = strings.TrimSuffix(upper, "MB")
+	case strings.HasSuffix(upper, "KB"):
+		multiplier = 1 << 10
+		upper = strings.TrimSuffix(upper, "KB")
+	case strings.HasSuffix(upper, "B"):
+		upper = strings.TrimSuffix(upper, "B")
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(upper), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max_size %q", s)
+	}
+	return n * multiplier, nil
+}
+
 
 
 
 
 func mapUri(ptr interface{}, m map[string][]string) error {
-	return mapFormByTag(ptr, m, "uri")
+	return mapFormByTag(ptr, m, "uri", nil)
 }
 
 func mapForm(ptr interface{}, form map[string][]string) error {
-	return mapFormByTag(ptr, form, "form")
+	return mapFormByTag(ptr, form, "form", nil)
+}
+
+// MapFormWithTag binds form into ptr the same way mapForm does, except it
+// reads tagName instead of gin's built-in "form"/"uri" struct tags. Useful
+// for structs shared across layers that already carry a different tag
+// (e.g. "query" or "param") and shouldn't have to duplicate "form" tags
+// just to satisfy gin's fixed names.
+func MapFormWithTag(ptr interface{}, form map[string][]string, tagName string) error {
+	return mapFormByTag(ptr, form, tagName, nil)
+}
+
+// FieldBindError records the field that failed to bind during a
+// MapFormPartial call, and why.
+type FieldBindError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldBindError) Error() string {
+	return fmt.Sprintf("field %q: %v", e.Field, e.Err)
+}
+
+// MapFormErrors collects every FieldBindError produced by MapFormPartial.
+type MapFormErrors []*FieldBindError
+
+func (e MapFormErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// MapFormPartial binds as much of form into ptr as it can, continuing past
+// a field that fails to bind instead of aborting, so the handler can still
+// use the fields that did bind. It returns the accumulated failures as a
+// MapFormErrors, or nil if every field bound successfully.
+func MapFormPartial(ptr interface{}, form map[string][]string) error {
+	var errs MapFormErrors
+	mapFormByTag(ptr, form, "form", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// MapFormWithAliases binds form the same way mapForm does, except incoming
+// keys are first renamed according to aliases (incoming key -> struct
+// field's "form" tag value). Keys with no entry in aliases pass through
+// unchanged. Useful for multi-tenant APIs where different clients send
+// the same logical field under different names.
+func MapFormWithAliases(ptr interface{}, form map[string][]string, aliases map[string]string) error {
+	renamed := make(map[string][]string, len(form))
+	for key, values := range form {
+		if alias, ok := aliases[key]; ok {
+			key = alias
+		}
+		renamed[key] = append(renamed[key], values...)
+	}
+	return mapForm(ptr, renamed)
 }
 
 
 
 
-func mapFormByTag(ptr interface{}, form map[string][]string, tag string) error {
+// MissingRequiredFieldsError lists every "binding:required" field that
+// mapFormByTag found missing from the submitted form, so a caller gets one
+// consistent message naming all of them instead of the first in isolation.
+type MissingRequiredFieldsError []string
 
+func (e MissingRequiredFieldsError) Error() string {
+	return fmt.Sprintf("missing required field(s): %s", strings.Join(e, ", "))
+}
 
-	typ := reflect.TypeOf(ptr) .Elem()  //获取变量类型，返回reflect.Type类型 
-	val := reflect.ValueOf(ptr).Elem()	//获取变量的值，返回reflect.Value类型 
+// failMissingRequired records a missing "binding:required" field: in
+// partial mode it's added to errs like any other field failure; otherwise
+// it's appended to missing so every missing field is reported together
+// once the whole form has been walked, rather than aborting on the first.
+func failMissingRequired(fieldName string, errs *MapFormErrors, missing *[]string) {
+	if errs != nil {
+		*errs = append(*errs, &FieldBindError{Field: fieldName, Err: errors.New("missing required field")})
+		return
+	}
+	*missing = append(*missing, fieldName)
+}
+
+// mapFormByTag binds form into ptr by the given struct tag. When errs is
+// non-nil, a field that fails to bind is recorded there and the loop moves
+// on to the next field (MapFormPartial's mode); when errs is nil, the first
+// error aborts the whole bind, as mapForm/mapUri have always done.
+//
+// A field tagged "binding:required" that the form omits is always detected
+// before validate() runs: in partial mode it's recorded like any other
+// field failure, and otherwise every missing required field is collected
+// and reported together as a MissingRequiredFieldsError once the rest of
+// the form has bound successfully.
+func mapFormByTag(ptr interface{}, form map[string][]string, tag string, errs *MapFormErrors) error {
+	var missing []string
+	if err := mapFormByTagRequired(ptr, form, tag, errs, &missing); err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return MissingRequiredFieldsError(missing)
+	}
+	return nil
+}
+
+func mapFormByTagRequired(ptr interface{}, form map[string][]string, tag string, errs *MapFormErrors, missing *[]string) error {
+
+
+	typ := reflect.TypeOf(ptr) .Elem()  //获取变量类型，返回reflect.Type类型
+	val := reflect.ValueOf(ptr).Elem()	//获取变量的值，返回reflect.Value类型
 
 
 	//遍历结构体属性
@@ -43,9 +256,46 @@ func mapFormByTag(ptr interface{}, form map[string][]string, tag string) error {
 			continue
 		}
 
-		structFieldKind 	:= structField.Kind() 					//获取属性类别，返回一个常量 
+		structFieldKind 	:= structField.Kind() 					//获取属性类别，返回一个常量
+
+		// An anonymous (embedded) struct or struct pointer field always has
+		// its fields flattened into the parent's namespace, regardless of
+		// whether the embedding itself happens to carry a tag -- e.g.
+		// `type Req struct { Pagination; Filter }` binds "page"/"size"/"name"
+		// straight off the flat form, the same as if Pagination and Filter's
+		// fields were declared directly on Req. A nil pointer embed is
+		// allocated on demand so its fields have somewhere to land.
+		if typeField.Anonymous {
+			embedded := structField
+			embeddedKind := structFieldKind
+			if embeddedKind == reflect.Ptr {
+				if !embedded.Elem().IsValid() {
+					embedded.Set(reflect.New(embedded.Type().Elem()))
+				}
+				embedded = embedded.Elem()
+				embeddedKind = embedded.Kind()
+			}
+			if embeddedKind == reflect.Struct {
+				if err := mapFormByTagRequired(embedded.Addr().Interface(), form, tag, errs, missing); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
 		inputFieldName 		:= typeField.Tag.Get(tag)				//获取属性tag，比如tag="json"或者tag="yaml"
 
+		// fail records err against this field and tells the caller whether
+		// to keep going: in partial mode (errs != nil) it always does;
+		// otherwise it signals the loop to abort by returning err.
+		fail := func(err error) error {
+			if errs != nil {
+				*errs = append(*errs, &FieldBindError{Field: typeField.Name, Err: err})
+				return nil
+			}
+			return err
+		}
+
 
 		////** 这块逻辑不用看，一般用不到 **/////
 		inputFieldNameList 	:= strings.Split(inputFieldName, ",") 	//
@@ -77,8 +327,7 @@ func mapFormByTag(ptr interface{}, form map[string][]string, tag string) error {
 
 			//如果属性是结构体类型，那么递归～～～
 			if structFieldKind == reflect.Struct {
-				err := mapFormByTag(structField.Addr().Interface(), form, tag)
-				if err != nil {
+				if err := mapFormByTagRequired(structField.Addr().Interface(), form, tag, errs, missing); err != nil {
 					return err
 				}
 				continue
@@ -87,11 +336,48 @@ func mapFormByTag(ptr interface{}, form map[string][]string, tag string) error {
 			//其他类型，不予处理
 		}
 
+		// a map field is populated from "name[subkey]=value" form keys rather
+		// than a single "name" key, so it's handled separately from the
+		// scalar/slice/struct lookup below. A field with no matching key is
+		// left nil, not an allocated-but-empty map.
+		if structFieldKind == reflect.Map {
+			if err := setMapField(form, inputFieldName, structField, fail); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// a []T field whose element type is a struct is populated from
+		// indexed "name[0].sub=value" form keys rather than one "name" key
+		// per element, so it's handled separately from the plain scalar
+		// slice case below. A field with no matching indexed key is left
+		// as a nil slice.
+		if structFieldKind == reflect.Slice && structField.Type().Elem().Kind() == reflect.Struct {
+			if err := setSliceOfStructField(form, inputFieldName, structField, tag, errs, missing, fail); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// "binding:required" is enforced by the mapper itself, ahead of
+		// validate(), so every binder reports a missing field the same way.
+		// A present "default=" option counts as satisfying it.
+		required := false
+		for _, opt := range strings.Split(typeField.Tag.Get("binding"), ",") {
+			if opt == "required" {
+				required = true
+				break
+			}
+		}
+
 		//从form参数表中取inputFieldName对应值
 		inputValue, exists := form[inputFieldName]
 		//若不存在，使用默认值
 		if !exists {
 			if defaultValue == "" {
+				if required {
+					failMissingRequired(inputFieldName, errs, missing)
+				}
 				continue
 			}
 			inputValue = make([]string, 1)
@@ -101,6 +387,74 @@ func mapFormByTag(ptr interface{}, form map[string][]string, tag string) error {
 		numElems := len(inputValue)
 		//如果inputValue是个数组，且结构体属性是切片类型，那么:
 		if structFieldKind == reflect.Slice && numElems > 0 {
+			// A slice-kind field that is itself a TextUnmarshaler (net.IP is
+			// defined as []byte) binds as one scalar value, not element by
+			// element, so it's handled before collection_format splitting or
+			// the generic per-element loop below ever sees it.
+			if handled, err := setByTextUnmarshaler(inputValue[0], structField); handled {
+				if err != nil {
+					if err := fail(err); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			// collection_format:"csv"/"ssv"/"pipes" lets repeated query/form
+			// params mix single and delimiter-joined values, e.g.
+			// "ids=1,2&ids=3" binds the same as "ids=1&ids=2&ids=3" under
+			// "csv" -- each repeated value is split on the delimiter and the
+			// results concatenated. Trailing separators produce empty
+			// elements, which are skipped unless collection_format carries a
+			// "_strict" suffix (e.g. "csv_strict"), in which case they're a
+			// binding error.
+			if format := typeField.Tag.Get("collection_format"); format != "" && format != "nl" {
+				strict := strings.HasSuffix(format, "_strict")
+				delim, ok := collectionFormatDelimiters[strings.TrimSuffix(format, "_strict")]
+				if ok {
+					flattened := make([]string, 0, numElems)
+					for _, v := range inputValue {
+						for _, part := range strings.Split(v, delim) {
+							if part == "" {
+								if strict {
+									if err := fail(fmt.Errorf("empty element in %s value", format)); err != nil {
+										return err
+									}
+								}
+								continue
+							}
+							flattened = append(flattened, part)
+						}
+					}
+					inputValue = flattened
+					numElems = len(inputValue)
+				}
+			} else if format == "nl" {
+				// collection_format:"nl" binds a single newline-delimited
+				// value (e.g. a textarea submission) into one element per
+				// line, handling both "\n" and "\r\n". A trailing newline
+				// produces a trailing empty line, which is dropped rather
+				// than becoming a spurious empty element.
+				flattened := make([]string, 0, numElems)
+				for _, v := range inputValue {
+					lines := strings.Split(strings.ReplaceAll(v, "\r\n", "\n"), "\n")
+					for len(lines) > 0 && lines[len(lines)-1] == "" {
+						lines = lines[:len(lines)-1]
+					}
+					flattened = append(flattened, lines...)
+				}
+				inputValue = flattened
+				numElems = len(inputValue)
+			}
+
+			// an empty slice (e.g. "ids=" with nothing left after csv/nl
+			// flattening) counts as missing for a required field, same as
+			// the key being absent entirely.
+			if required && numElems == 0 && defaultValue == "" {
+				failMissingRequired(inputFieldName, errs, missing)
+				continue
+			}
+
 			//获取切片数组的元素类型（sliceOf）
 			sliceOf := structField.Type().Elem().Kind()
 			//创建指定大小的切片
@@ -109,7 +463,10 @@ func mapFormByTag(ptr interface{}, form map[string][]string, tag string) error {
 			for i := 0; i < numElems; i++ {
 				//根据元素类型，元素值，切片下标逐个赋值
 				if err := setWithProperType(sliceOf, inputValue[i], slice.Index(i)); err != nil {
-					return err
+					if err := fail(err); err != nil {
+						return err
+					}
+					continue
 				}
 			}
 			//设置外层结构的属性值，这里的i是外层循环的i
@@ -117,107 +474,404 @@ func mapFormByTag(ptr interface{}, form map[string][]string, tag string) error {
 			continue
 		}
 
+		// a fixed-size [N]T field, parallel to the []T branch above but
+		// against a length that's already fixed by the type: up to arrLen
+		// input values are converted in place with setWithProperType, and
+		// any slots beyond the submitted values are left at their zero
+		// value. A submission with more values than the array can hold is
+		// silently truncated by default, or rejected with an error when
+		// ArrayOverflowError is installed via SetArrayOverflowPolicy.
+		if structFieldKind == reflect.Array && numElems > 0 {
+			arrLen := structField.Len()
+			if numElems > arrLen {
+				if arrayOverflowPolicy == ArrayOverflowError {
+					if err := fail(fmt.Errorf("too many values for field %q: got %d, array holds %d", inputFieldName, numElems, arrLen)); err != nil {
+						return err
+					}
+				}
+				numElems = arrLen
+			}
+
+			elemKind := structField.Type().Elem().Kind()
+			for i := 0; i < numElems; i++ {
+				if err := setWithProperType(elemKind, inputValue[i], structField.Index(i)); err != nil {
+					if err := fail(err); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			continue
+		}
+
 		//如果结构体属性类型是时间类型，那么需要进行相应格式转换
 		if _, isTime := structField.Interface().(time.Time); isTime {
 			//根据元素类型，元素值，结构体属性字段进行赋值
 			if err := setTimeField(inputValue[0], typeField, structField); err != nil {
-				return err
+				if err := fail(err); err != nil {
+					return err
+				}
 			}
 			continue
 		}
 
+		// if the field is a pointer and the input value matches the configured
+		// null_literal tag, set it to nil instead of parsing it as a normal value.
+		if structFieldKind == reflect.Ptr {
+			if nullLiteral := typeField.Tag.Get("null_literal"); nullLiteral != "" && inputValue[0] == nullLiteral {
+				structField.Set(reflect.Zero(structField.Type()))
+				continue
+			}
+		}
+
 		//根据元素类型，元素值，结构体属性字段进行赋值
-		if err := setWithProperType(typeField.Type.Kind(), inputValue[0], structField); err != nil {
+		if err := setWithProperTypeAndDefault(typeField.Type.Kind(), inputValue[0], structField, defaultValue); err != nil {
+			if err := fail(err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ParseBracketedKeys collects every "prefix[subkey]=value" entry of form
+// into a plain map from subkey to the entry's first value, e.g.
+// ParseBracketedKeys(form, "meta") turns "meta[color]=red&meta[size]=large"
+// into {"color": "red", "size": "large"}. It's the bracket-parsing
+// primitive shared by setMapField's map[K]V form binding and gin.Context's
+// QueryMap/PostFormMap, so the two don't disagree on what counts as a
+// bracketed key. A prefix with no matching keys returns an empty, non-nil
+// map.
+func ParseBracketedKeys(form map[string][]string, prefix string) map[string]string {
+	bracketPrefix := prefix + "["
+	result := make(map[string]string)
+	for formKey, formValues := range form {
+		if len(formValues) == 0 || !strings.HasPrefix(formKey, bracketPrefix) || !strings.HasSuffix(formKey, "]") {
+			continue
+		}
+		subKey := formKey[len(bracketPrefix) : len(formKey)-1]
+		if subKey == "" {
+			continue
+		}
+		result[subKey] = formValues[0]
+	}
+	return result
+}
+
+// setMapField populates a map[K]V field tagged e.g. form:"meta" from form
+// keys following the "meta[subkey]=value" convention, such as
+// "meta[color]=red&meta[size]=large". Map keys and values are converted to
+// K and V with setWithProperType, same as scalar fields. A fail that isn't
+// aborting the whole bind just skips that one entry.
+func setMapField(form map[string][]string, inputFieldName string, structField reflect.Value, fail func(error) error) error {
+	raw := ParseBracketedKeys(form, inputFieldName)
+
+	mapType := structField.Type()
+	keyKind := mapType.Key().Kind()
+	elemKind := mapType.Elem().Kind()
+
+	var mapVal reflect.Value
+	for subKey, value := range raw {
+		keyVal := reflect.New(mapType.Key()).Elem()
+		if err := setWithProperType(keyKind, subKey, keyVal); err != nil {
+			if err := fail(err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		elemVal := reflect.New(mapType.Elem()).Elem()
+		if err := setWithProperType(elemKind, value, elemVal); err != nil {
+			if err := fail(err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !mapVal.IsValid() {
+			mapVal = reflect.MakeMap(mapType)
+		}
+		mapVal.SetMapIndex(keyVal, elemVal)
+	}
+
+	if mapVal.IsValid() {
+		structField.Set(mapVal)
+	}
+	return nil
+}
+
+// maxSliceOfStructIndex caps the highest "items[N]." index setSliceOfStructField
+// will honor. Without a cap, a single small POST like "items[999999999].name=a"
+// would drive reflect.MakeSlice to allocate hundreds of millions of struct
+// elements -- a trivial memory-exhaustion DoS, since the index comes straight
+// from the client.
+const maxSliceOfStructIndex = 10000
+
+// setSliceOfStructField populates a []T field (T a struct) tagged e.g.
+// form:"items" from indexed keys such as "items[0].name=a&items[1].name=b".
+// The set of indices present is discovered first so gaps are tolerated: a
+// sparse form produces a slice sized to the highest index seen, with unset
+// indices left as the zero value, rather than one entry per key found. Each
+// element is then bound by recursing into mapFormByTagRequired with an
+// "items[N]." key namespace stripped off, so the existing scalar/slice/time
+// handling (including setWithProperType for the leaves) applies unchanged.
+// An index beyond maxSliceOfStructIndex is reported via fail and otherwise
+// ignored, rather than trusted to size the allocation.
+func setSliceOfStructField(form map[string][]string, inputFieldName string, structField reflect.Value, tag string, errs *MapFormErrors, missing *[]string, fail func(error) error) error {
+	prefix := inputFieldName + "["
+
+	indexSet := make(map[int]bool)
+	for formKey := range form {
+		if !strings.HasPrefix(formKey, prefix) {
+			continue
+		}
+		rest := formKey[len(prefix):]
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			continue
+		}
+		idx, err := strconv.Atoi(rest[:end])
+		if err != nil || idx < 0 {
+			continue
+		}
+		if idx > maxSliceOfStructIndex {
+			if err := fail(fmt.Errorf("index %d for field %q exceeds the maximum of %d", idx, inputFieldName, maxSliceOfStructIndex)); err != nil {
+				return err
+			}
+			continue
+		}
+		indexSet[idx] = true
+	}
+	if len(indexSet) == 0 {
+		return nil
+	}
+
+	maxIdx := 0
+	for idx := range indexSet {
+		if idx > maxIdx {
+			maxIdx = idx
+		}
+	}
+
+	elemType := structField.Type().Elem()
+	slice := reflect.MakeSlice(structField.Type(), maxIdx+1, maxIdx+1)
+	for idx := range indexSet {
+		elemPrefix := fmt.Sprintf("%s[%d].", inputFieldName, idx)
+		elemForm := make(map[string][]string, len(form))
+		for formKey, formValues := range form {
+			if strings.HasPrefix(formKey, elemPrefix) {
+				elemForm[formKey[len(elemPrefix):]] = formValues
+			}
+		}
+
+		elemPtr := reflect.New(elemType)
+		if err := mapFormByTagRequired(elemPtr.Interface(), elemForm, tag, errs, missing); err != nil {
 			return err
 		}
+		slice.Index(idx).Set(elemPtr.Elem())
 	}
+
+	structField.Set(slice)
 	return nil
 }
 
+// EmptyValuePolicy controls what setIntField, setUintField, setBoolField and
+// setFloatField do with an empty string value, e.g. a submitted "int_foo="
+// with nothing after the "=". Before this existed, each setter coerced
+// empty to its own type's zero value independently; the policy makes that
+// one explicit, uniform choice instead of four parallel implementations.
+type EmptyValuePolicy int
+
+const (
+	// EmptyValueCoerceZero (the default) treats an empty value as the
+	// field's zero value: "" -> 0, 0.0 or false, unless the field carries a
+	// "default=" tag option (see mapFormByTag), in which case the default
+	// wins -- a browser submitting "age=" for an untouched field shouldn't
+	// silently override a configured default of 18.
+	EmptyValueCoerceZero EmptyValuePolicy = iota
+	// EmptyValueError rejects an empty value, requiring every key that's
+	// present in the form to carry a real value.
+	EmptyValueError
+	// EmptyValueDefault is now equivalent to EmptyValueCoerceZero: both
+	// prefer the field's "default=" tag option when present and fall back
+	// to the zero value otherwise. Kept as a distinct constant for existing
+	// callers that set it explicitly.
+	EmptyValueDefault
+)
+
+var emptyValuePolicy = EmptyValueCoerceZero
+
+// SetEmptyValuePolicy installs the policy applied by setIntField,
+// setUintField, setBoolField and setFloatField whenever they're asked to
+// bind an empty string. Affects every scalar binder (form, query, uri).
+func SetEmptyValuePolicy(policy EmptyValuePolicy) {
+	emptyValuePolicy = policy
+}
+
+// resolveEmptyValue applies emptyValuePolicy to val, given zero (this
+// type's zero-value literal) and defaultValue (the field's "default="
+// option, "" if none). val is returned unchanged when it isn't empty.
+func resolveEmptyValue(val, zero, defaultValue string) (string, error) {
+	if val != "" {
+		return val, nil
+	}
+	if emptyValuePolicy == EmptyValueError {
+		return "", errors.New("empty value not allowed")
+	}
+	if defaultValue != "" {
+		return defaultValue, nil
+	}
+	return zero, nil
+}
+
+// ArrayOverflowPolicy controls what the [N]T array branch of
+// mapFormByTagRequired does when a form key submits more values than the
+// array can hold.
+type ArrayOverflowPolicy int
+
+const (
+	// ArrayOverflowTruncate (the default) keeps the first len(array) values
+	// and silently drops the rest.
+	ArrayOverflowTruncate ArrayOverflowPolicy = iota
+	// ArrayOverflowError rejects a submission that overflows the array,
+	// reporting it the same way any other per-field bind error is reported.
+	ArrayOverflowError
+)
+
+var arrayOverflowPolicy = ArrayOverflowTruncate
+
+// SetArrayOverflowPolicy installs the policy applied when a fixed-size
+// array field is bound from more form values than it has room for.
+func SetArrayOverflowPolicy(policy ArrayOverflowPolicy) {
+	arrayOverflowPolicy = policy
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// setByTextUnmarshaler binds val into structField via encoding.TextUnmarshaler
+// when the field (or, for a nil pointer field, its newly-allocated element)
+// implements it -- the general hook for types like net.IP or a custom enum
+// that know how to parse their own textual form, which the built-in scalar
+// kinds below know nothing about. Returns false when neither the field nor
+// its pointer implements the interface, so the caller falls back to the
+// plain conversion; a nil pointer field has already been allocated by then.
+func setByTextUnmarshaler(val string, structField reflect.Value) (bool, error) {
+	fieldValue := structField
+	if fieldValue.Kind() == reflect.Ptr {
+		if fieldValue.IsNil() {
+			fieldValue.Set(reflect.New(fieldValue.Type().Elem()))
+		}
+		fieldValue = fieldValue.Elem()
+	}
+	if !fieldValue.CanAddr() {
+		return false, nil
+	}
+	addr := fieldValue.Addr()
+	if !addr.Type().Implements(textUnmarshalerType) {
+		return false, nil
+	}
+	return true, addr.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+}
+
 func setWithProperType(valueKind reflect.Kind, val string, structField reflect.Value) error {
+	return setWithProperTypeAndDefault(valueKind, val, structField, "")
+}
+
+func setWithProperTypeAndDefault(valueKind reflect.Kind, val string, structField reflect.Value, defaultValue string) error {
+	if handled, err := setByTextUnmarshaler(val, structField); handled {
+		return err
+	}
 	switch valueKind {
 	case reflect.Int:
-		return setIntField(val, 0, structField)
+		return setIntField(val, 0, structField, defaultValue)
 	case reflect.Int8:
-		return setIntField(val, 8, structField)
+		return setIntField(val, 8, structField, defaultValue)
 	case reflect.Int16:
-		return setIntField(val, 16, structField)
+		return setIntField(val, 16, structField, defaultValue)
 	case reflect.Int32:
-		return setIntField(val, 32, structField)
+		return setIntField(val, 32, structField, defaultValue)
 	case reflect.Int64:
-		return setIntField(val, 64, structField)
+		return setIntField(val, 64, structField, defaultValue)
 	case reflect.Uint:
-		return setUintField(val, 0, structField)
+		return setUintField(val, 0, structField, defaultValue)
 	case reflect.Uint8:
-		return setUintField(val, 8, structField)
+		return setUintField(val, 8, structField, defaultValue)
 	case reflect.Uint16:
-		return setUintField(val, 16, structField)
+		return setUintField(val, 16, structField, defaultValue)
 	case reflect.Uint32:
-		return setUintField(val, 32, structField)
+		return setUintField(val, 32, structField, defaultValue)
 	case reflect.Uint64:
-		return setUintField(val, 64, structField)
+		return setUintField(val, 64, structField, defaultValue)
 	case reflect.Bool:
-		return setBoolField(val, structField)
+		return setBoolField(val, structField, defaultValue)
 	case reflect.Float32:
-		return setFloatField(val, 32, structField)
+		return setFloatField(val, 32, structField, defaultValue)
 	case reflect.Float64:
-		return setFloatField(val, 64, structField)
+		return setFloatField(val, 64, structField, defaultValue)
 	case reflect.String:
-		structField.SetString(val)
+		structField.SetString(stringPreprocessor(val))
 	case reflect.Ptr:
 		if !structField.Elem().IsValid() {
 			structField.Set(reflect.New(structField.Type().Elem()))
 		}
 		structFieldElem := structField.Elem()
-		return setWithProperType(structFieldElem.Kind(), val, structFieldElem)
+		return setWithProperTypeAndDefault(structFieldElem.Kind(), val, structFieldElem, defaultValue)
 	default:
 		return errors.New("Unknown type")
 	}
 	return nil
 }
 
-func setIntField(val string, bitSize int, field reflect.Value) error {
-	if val == "" {
-		val = "0"
+func setIntField(val string, bitSize int, field reflect.Value, defaultValue string) error {
+	resolved, err := resolveEmptyValue(val, "0", defaultValue)
+	if err != nil {
+		return err
 	}
-	intVal, err := strconv.ParseInt(val, 10, bitSize)
-	if err == nil {
-		field.SetInt(intVal)
+	intVal, err := strconv.ParseInt(resolved, 10, bitSize)
+	if err != nil {
+		return fmt.Errorf("cannot parse %q as int", val)
 	}
-	return err
+	field.SetInt(intVal)
+	return nil
 }
 
-func setUintField(val string, bitSize int, field reflect.Value) error {
-	if val == "" {
-		val = "0"
+func setUintField(val string, bitSize int, field reflect.Value, defaultValue string) error {
+	resolved, err := resolveEmptyValue(val, "0", defaultValue)
+	if err != nil {
+		return err
 	}
-	uintVal, err := strconv.ParseUint(val, 10, bitSize)
-	if err == nil {
-		field.SetUint(uintVal)
+	uintVal, err := strconv.ParseUint(resolved, 10, bitSize)
+	if err != nil {
+		return fmt.Errorf("cannot parse %q as uint", val)
 	}
-	return err
+	field.SetUint(uintVal)
+	return nil
 }
 
-func setBoolField(val string, field reflect.Value) error {
-	if val == "" {
-		val = "false"
+func setBoolField(val string, field reflect.Value, defaultValue string) error {
+	resolved, err := resolveEmptyValue(val, "false", defaultValue)
+	if err != nil {
+		return err
 	}
-	boolVal, err := strconv.ParseBool(val)
-	if err == nil {
-		field.SetBool(boolVal)
+	boolVal, err := strconv.ParseBool(resolved)
+	if err != nil {
+		return fmt.Errorf("cannot parse %q as bool", val)
 	}
-	return err
+	field.SetBool(boolVal)
+	return nil
 }
 
-func setFloatField(val string, bitSize int, field reflect.Value) error {
-	if val == "" {
-		val = "0.0"
+func setFloatField(val string, bitSize int, field reflect.Value, defaultValue string) error {
+	resolved, err := resolveEmptyValue(val, "0.0", defaultValue)
+	if err != nil {
+		return err
 	}
-	floatVal, err := strconv.ParseFloat(val, bitSize)
-	if err == nil {
-		field.SetFloat(floatVal)
+	floatVal, err := strconv.ParseFloat(resolved, bitSize)
+	if err != nil {
+		return fmt.Errorf("cannot parse %q as float", val)
 	}
-	return err
+	field.SetFloat(floatVal)
+	return nil
 }
 
 func setTimeField(val string, structField reflect.StructField, value reflect.Value) error {
@@ -244,7 +898,35 @@ func setTimeField(val string, structField reflect.StructField, value reflect.Val
 		l = loc
 	}
 
-	t, err := time.ParseInLocation(timeFormat, val, l)
+	// time_format:"unix"/"unixNano" are sentinels rather than layouts: the
+	// value is epoch seconds/nanoseconds parsed with strconv.ParseInt and
+	// turned into a time.Time with time.Unix, then moved into l like any
+	// other layout below.
+	if timeFormat == "unix" || timeFormat == "unixNano" {
+		epoch, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return err
+		}
+		if timeFormat == "unixNano" {
+			value.Set(reflect.ValueOf(time.Unix(0, epoch).In(l)))
+		} else {
+			value.Set(reflect.ValueOf(time.Unix(epoch, 0).In(l)))
+		}
+		return nil
+	}
+
+	// time_format may list several comma-separated layouts, e.g.
+	// "2006-01-02T15:04:05Z07:00,2006-01-02" to accept both RFC3339 and a
+	// plain date from different upstreams. They're tried in order and the
+	// first to parse wins; if none do, the last layout's error is returned.
+	var t time.Time
+	var err error
+	for _, layout := range strings.Split(timeFormat, ",") {
+		t, err = time.ParseInLocation(layout, val, l)
+		if err == nil {
+			break
+		}
+	}
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,32 @@
+// Copyright 2018 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package binding
+
+import "net/textproto"
+
+type headerBinding struct{}
+
+func (headerBinding) Name() string {
+	return "header"
+}
+
+// BindHeader maps header, a request's http.Header, onto obj using its
+// "header" struct tags, e.g. `header:"X-Request-Id"`. HTTP header names are
+// case-insensitive, so every incoming key is canonicalized with
+// textproto.CanonicalMIMEHeaderKey before matching -- a header sent as
+// "x-request-id" still finds a field tagged "X-Request-Id". Tags are matched
+// as written and are not canonicalized, so a tag only matches when it is
+// itself in canonical form. A header sent multiple times binds into a slice
+// field the same way a repeated form key does.
+func (headerBinding) BindHeader(header map[string][]string, obj interface{}) error {
+	canonical := make(map[string][]string, len(header))
+	for key, values := range header {
+		canonical[textproto.CanonicalMIMEHeaderKey(key)] = values
+	}
+	if err := mapFormByTag(obj, canonical, "header", nil); err != nil {
+		return err
+	}
+	return validate(obj)
+}
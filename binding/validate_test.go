@@ -234,3 +234,25 @@ func TestValidatorEngine(t *testing.T) {
 	// Check that the error matches expectation
 	assert.Error(t, errs, "", "", "notone")
 }
+
+type structMultiFieldValidation struct {
+	Email string `binding:"required"`
+	Age   int    `binding:"min=18"`
+}
+
+func TestValidateStructReturnsFieldErrorsForEveryFailure(t *testing.T) {
+	err := validate(structMultiFieldValidation{Age: 10})
+
+	errs, ok := err.(ValidationErrors)
+	assert.True(t, ok)
+	assert.Len(t, errs, 2)
+
+	byField := make(map[string]*FieldError, len(errs))
+	for _, fe := range errs {
+		byField[fe.Field] = fe
+	}
+
+	assert.Equal(t, "required", byField["Email"].Tag)
+	assert.Equal(t, "min", byField["Age"].Tag)
+	assert.Equal(t, 10, byField["Age"].Value)
+}
@@ -0,0 +1,44 @@
+// Copyright 2014 Manu Martinez-Almeida.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+)
+
+// BodyBytes returns a middleware that reads the whole request body into
+// memory (bounded by limit bytes; limit <= 0 means unlimited) and replaces
+// req.Body with a fresh reader over the buffered bytes. This lets handlers
+// and other middleware read the raw body more than once, and lets the
+// form/JSON binding path (ShouldBindWith) restore the body after binding
+// instead of exhausting it.
+//
+// The buffered bytes are stashed under BodyBytesKey, the same key
+// ShouldBindBodyWith already honors.
+func BodyBytes(limit int64) HandlerFunc {
+	return func(c *Context) {
+		if c.Request == nil || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		var reader io.Reader = c.Request.Body
+		if limit > 0 {
+			reader = io.LimitReader(reader, limit)
+		}
+		body, err := ioutil.ReadAll(reader)
+		c.Request.Body.Close()
+		if err != nil {
+			c.AbortWithError(500, err)
+			return
+		}
+
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		c.Set(BodyBytesKey, body)
+		c.Next()
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2014 Manu Martinez-Almeida.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyBytesRereadAfterBind(t *testing.T) {
+	const payload = `{"foo":"bar"}`
+
+	router := New()
+	router.Use(BodyBytes(0))
+
+	var raw string
+	router.POST("/ping", func(c *Context) {
+		var obj struct {
+			Foo string `json:"foo"`
+		}
+		assert.NoError(t, c.ShouldBindJSON(&obj))
+		assert.Equal(t, "bar", obj.Foo)
+
+		b, err := ioutil.ReadAll(c.Request.Body)
+		assert.NoError(t, err)
+		raw = string(b)
+	})
+
+	req := httptest.NewRequest("POST", "/ping", bytes.NewBufferString(payload))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, payload, raw)
+}
+
+func TestBodyBytesLimit(t *testing.T) {
+	router := New()
+	router.Use(BodyBytes(4))
+
+	var raw string
+	router.POST("/ping", func(c *Context) {
+		b, err := ioutil.ReadAll(c.Request.Body)
+		assert.NoError(t, err)
+		raw = string(b)
+	})
+
+	req := httptest.NewRequest("POST", "/ping", bytes.NewBufferString("0123456789"))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0123", raw)
+}
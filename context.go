@@ -5,6 +5,7 @@
 package gin
 
 import (
+	"bytes"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -14,8 +15,10 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin/binding"
@@ -32,6 +35,7 @@ const (
 	MIMEPOSTForm          = binding.MIMEPOSTForm
 	MIMEMultipartPOSTForm = binding.MIMEMultipartPOSTForm
 	MIMEYAML              = binding.MIMEYAML
+	MIMEYAML2             = binding.MIMEYAML2
 	BodyBytesKey          = "_gin-gonic/gin/bodybyteskey"
 )
 
@@ -59,6 +63,7 @@ type Context struct {
 	Params   params 			// 路径当中的参数
 	handlers HandlersChain		// 处理函数数组
 	index    int8 				// 当前在运行着第几个处理函数
+	fullPath string				// 匹配到的原始注册路由模板，例如`/users/:id`
 
 	engine *Engine
 
@@ -70,6 +75,15 @@ type Context struct {
 
 	// Accepted defines a list of manually accepted formats for content negotiation.
 	Accepted []string
+
+	// sameSite is the SameSite attribute applied to cookies set via SetCookie,
+	// configured per-request with SetSameSite.
+	sameSite http.SameSite
+
+	// rewriteDepth counts how many times HandleContext has re-entered
+	// routing for this context, so it can refuse to loop forever on a
+	// misconfigured rewrite rule. Reset to 0 for every new top-level request.
+	rewriteDepth int
 }
 
 
@@ -86,9 +100,12 @@ func (c *Context) reset() {
 	c.Params = c.Params[0:0]
 	c.handlers = nil
 	c.index = -1
+	c.fullPath = ""
 	c.Keys = nil
 	c.Errors = c.Errors[0:0]
 	c.Accepted = nil
+	c.sameSite = 0
+	c.rewriteDepth = 0
 }
 
 
@@ -116,6 +133,15 @@ func (c *Context) Handler() HandlerFunc {
 	return c.handlers.Last()
 }
 
+// FullPath returns a matched route full path. For not found routes
+// returns an empty string.
+//     router.GET("/user/:id", func(c *gin.Context) {
+//         c.FullPath() == "/user/:id" // true
+//     })
+func (c *Context) FullPath() string {
+	return c.fullPath
+}
+
 /************************************/
 /*********** FLOW CONTROL ***********/
 /************************************/
@@ -499,24 +525,12 @@ func (c *Context) GetPostFormMap(key string) (map[string]string, bool) {
 }
 
 // get is an internal method and returns a map which satisfy conditions.
+// get解析"key[subKey]=value"形式的bracket键，委托给binding包中setMapField
+// 绑定map[K]V字段时用的同一个ParseBracketedKeys，这样QueryMap/PostFormMap与
+// 表单的map字段绑定对bracket键的理解始终一致。
 func (c *Context) get(m map[string][]string, key string) (map[string]string, bool) {
-	dicts := make(map[string]string)
-	exist := false
-	for k, v := range m {
-		// k 			="PostForm[uid]"
-		// k[0:i] 		="PostForm"
-		// k[i+1:] 		="uid]"
-		// k[i+1:][:j] 	="uid"
-		// 因此，如果key的值是PostForm，那么就会取出uid这个subKey和v[0]存入参数dict中。
-		if i := strings.IndexByte(k, '['); i >= 1 && k[0:i] == key {
-			if j := strings.IndexByte(k[i+1:], ']'); j >= 1 {
-				exist = true
-				//注意，dicts[][]中存的不是key而是subKey
-				dicts[k[i+1:][:j]] = v[0]
-			}
-		}
-	}
-	return dicts, exist
+	dicts := binding.ParseBracketedKeys(m, key)
+	return dicts, len(dicts) > 0
 }
 
 
@@ -603,6 +617,56 @@ func (c *Context) BindUri(obj interface{}) error {
 	return nil
 }
 
+// BindHeader binds the passed struct pointer using binding.Header.
+// It will abort the request with HTTP 400 if any error occurs.
+func (c *Context) BindHeader(obj interface{}) error {
+	if err := c.ShouldBindHeader(obj); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err).SetType(ErrorTypeBind) // nolint: errcheck
+		return err
+	}
+	return nil
+}
+
+// BindAndValidate binds the request's form data into obj using
+// binding.MapFormPartial, the error-accumulating binder, instead of
+// aborting on the first bad field. On success it returns true. On failure
+// it aborts the request with a 422 whose JSON body maps each failed
+// field's name to its error message, and returns false so the handler can
+// bail out, e.g.:
+//
+//	if !c.BindAndValidate(&form) {
+//	    return
+//	}
+func (c *Context) BindAndValidate(obj interface{}) bool {
+	req := c.Request
+	if err := req.ParseForm(); err != nil {
+		c.AbortWithError(http.StatusUnprocessableEntity, err).SetType(ErrorTypeBind) // nolint: errcheck
+		return false
+	}
+	if err := req.ParseMultipartForm(c.engine.MaxMultipartMemory); err != nil && err != http.ErrNotMultipart {
+		c.AbortWithError(http.StatusUnprocessableEntity, err).SetType(ErrorTypeBind) // nolint: errcheck
+		return false
+	}
+
+	err := binding.MapFormPartial(obj, req.Form)
+	if err == nil {
+		return true
+	}
+
+	fieldErrs, ok := err.(binding.MapFormErrors)
+	if !ok {
+		c.AbortWithError(http.StatusUnprocessableEntity, err).SetType(ErrorTypeBind) // nolint: errcheck
+		return false
+	}
+
+	fields := make(map[string]string, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		fields[fe.Field] = fe.Err.Error()
+	}
+	c.AbortWithStatusJSON(http.StatusUnprocessableEntity, H{"errors": fields})
+	return false
+}
+
 
 
 // MustBindWith binds the passed struct pointer using the specified binding engine.
@@ -611,13 +675,24 @@ func (c *Context) BindUri(obj interface{}) error {
 func (c *Context) MustBindWith(obj interface{}, b binding.Binding) error {
 
 	if err := c.ShouldBindWith(obj, b); err != nil {
-		c.AbortWithError(http.StatusBadRequest, err).SetType(ErrorTypeBind) // nolint: errcheck
+		c.AbortWithError(bindErrorStatusCode(err), err).SetType(ErrorTypeBind) // nolint: errcheck
 		return err
 	}
 
 	return nil
 }
 
+// bindErrorStatusCode returns the status code MustBindWith should abort
+// with for err: 413 if the body was cut short by Engine.MaxRequestBodySize,
+// 400 otherwise.
+func bindErrorStatusCode(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
 // ShouldBind checks the Content-Type to select a binding engine automatically,
 // Depending the "Content-Type" header different bindings are used:
 //     "application/json" --> JSON binding
@@ -660,14 +735,57 @@ func (c *Context) ShouldBindUri(obj interface{}) error {
 	return binding.Uri.BindUri(m, obj)
 }
 
+// ShouldBindHeader binds the passed struct pointer using binding.Header.
+func (c *Context) ShouldBindHeader(obj interface{}) error {
+	return binding.Header.BindHeader(c.Request.Header, obj)
+}
+
 // ShouldBindWith binds the passed struct pointer using the specified binding engine.
 // See the binding package.
+//
+// A multipart/form-data bind parses the body with Engine.MaxMultipartMemory
+// rather than the binding package's own default, so Context.FormFile and a
+// multipart form binding agree on how much of the upload is buffered in
+// memory. The limit travels on c.Request's own context (see
+// binding.WithMaxMemory), not a package-level var, so two engines binding
+// concurrently never read or clobber each other's limit.
+//
+// If the request body was buffered by the BodyBytes middleware, req.Body is
+// restored to a fresh reader over the buffered bytes afterwards, so binding
+// never exhausts it for later reads (logging, re-binding, etc).
 func (c *Context) ShouldBindWith(obj interface{}, b binding.Binding) error {
-	return b.Bind(c.Request, obj)
+	if c.engine != nil {
+		c.Request = binding.WithMaxMemory(c.Request, c.engine.MaxMultipartMemory)
+	}
+	err := b.Bind(c.Request, obj)
+	c.restoreBufferedBody()
+	return err
+}
+
+// restoreBufferedBody resets c.Request.Body to a fresh reader over the bytes
+// buffered by the BodyBytes middleware, if any.
+func (c *Context) restoreBufferedBody() {
+	if c.Request == nil {
+		return
+	}
+	cb, ok := c.Get(BodyBytesKey)
+	if !ok {
+		return
+	}
+	body, ok := cb.([]byte)
+	if !ok {
+		return
+	}
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
 }
 
 // ShouldBindBodyWith is similar with ShouldBindWith, but it stores the request
-// body into the context, and reuse when it is called again.
+// body into the context, and reuse when it is called again. This lets a
+// handler try binding the same body against several candidate struct types
+// (a tagged-union-style dispatch) without later attempts seeing an
+// exhausted reader. The cached body is request-scoped: it's stored under
+// c.Keys like any other c.Set value, and c.reset() clears it along with
+// everything else before a pooled Context is handed to the next request.
 //
 // NOTE: This method reads the body before binding. So you should use
 // ShouldBindWith for better performance if you need to call only once.
@@ -690,18 +808,25 @@ func (c *Context) ShouldBindBodyWith(obj interface{}, bb binding.BindingBody) (e
 	return bb.BindBody(body, obj)
 }
 
-// ClientIP implements a best effort algorithm to return the real client IP, 
+// ClientIP implements a best effort algorithm to return the real client IP,
 // it parses X-Real-IP and X-Forwarded-For in order to work properly with reverse-proxies such us: nginx or haproxy.
 // Use X-Forwarded-For before X-Real-Ip as nginx uses X-Real-Ip with the proxy's IP.
+//
+// The forwarding headers are only honored when the request's immediate peer
+// (RemoteAddr) is in a network configured via Engine.SetTrustedProxies;
+// anyone can set X-Forwarded-For, so an unconfigured or non-matching peer
+// gets its literal RemoteAddr back regardless of ForwardedByClientIP. When
+// the peer is trusted, the X-Forwarded-For chain is walked right to left,
+// skipping further trusted-proxy hops, and the first untrusted (or leftmost)
+// entry is taken as the client IP.
 func (c *Context) ClientIP() string {
+	remoteIP, _, splitErr := net.SplitHostPort(strings.TrimSpace(c.Request.RemoteAddr))
 
-	if c.engine.ForwardedByClientIP {
-		clientIP := c.requestHeader("X-Forwarded-For")
-		clientIP = strings.TrimSpace(strings.Split(clientIP, ",")[0])
-		if clientIP == "" {
-			clientIP = strings.TrimSpace(c.requestHeader("X-Real-Ip"))
+	if splitErr == nil && c.engine.ForwardedByClientIP && c.engine.isTrustedProxy(net.ParseIP(remoteIP)) {
+		if clientIP := c.clientIPFromForwardedFor(); clientIP != "" {
+			return clientIP
 		}
-		if clientIP != "" {
+		if clientIP := strings.TrimSpace(c.requestHeader("X-Real-Ip")); clientIP != "" {
 			return clientIP
 		}
 	}
@@ -712,10 +837,33 @@ func (c *Context) ClientIP() string {
 		}
 	}
 
-	if ip, _, err := net.SplitHostPort(strings.TrimSpace(c.Request.RemoteAddr)); err == nil {
-		return ip
+	if splitErr == nil {
+		return remoteIP
 	}
+	return ""
+}
 
+// clientIPFromForwardedFor walks X-Forwarded-For from the rightmost (closest
+// to this server) entry leftwards, skipping over entries that are
+// themselves trusted proxies, and returns the first one that isn't -- the
+// earliest hop none of our trusted proxies can vouch for, and so the best
+// guess at the real client.
+func (c *Context) clientIPFromForwardedFor() string {
+	xff := c.requestHeader("X-Forwarded-For")
+	if xff == "" {
+		return ""
+	}
+	items := strings.Split(xff, ",")
+	for i := len(items) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(items[i])
+		if ip == "" {
+			continue
+		}
+		if i > 0 && c.engine.isTrustedProxy(net.ParseIP(ip)) {
+			continue
+		}
+		return ip
+	}
 	return ""
 }
 
@@ -757,7 +905,7 @@ func bodyAllowedForStatus(status int) bool {
 
 // Status sets the HTTP response code.
 func (c *Context) Status(code int) {
-	c.writermem.WriteHeader(code)
+	c.Writer.WriteHeader(code)
 }
 
 // Header is a intelligent shortcut for c.Writer.Header().Set(key, value).
@@ -777,9 +925,34 @@ func (c *Context) GetHeader(key string) string {
 	return c.requestHeader(key)
 }
 
-// GetRawData return stream data.
+// GetRawData reads and returns the entire request body, e.g. for an HMAC
+// signature-verification middleware that must see the exact raw bytes
+// before any binder touches the stream. Reading req.Body is normally a
+// one-shot operation, but GetRawData caches what it read under
+// BodyBytesKey (the same cache ShouldBindBodyWith consults) and replaces
+// c.Request.Body with a fresh reader over those bytes, so a bind call
+// later in the chain still sees the full body instead of an exhausted one.
 func (c *Context) GetRawData() ([]byte, error) {
-	return ioutil.ReadAll(c.Request.Body)
+	if cb, ok := c.Get(BodyBytesKey); ok {
+		if body, ok := cb.([]byte); ok {
+			return body, nil
+		}
+	}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.Set(BodyBytesKey, body)
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// SetSameSite sets the SameSite attribute (Lax, Strict, None or Default) that
+// SetCookie applies to every cookie it sets on this Context from then on.
+// Leaving it unset keeps the browser's default behavior.
+func (c *Context) SetSameSite(samesite http.SameSite) {
+	c.sameSite = samesite
 }
 
 // SetCookie adds a Set-Cookie header to the ResponseWriter's headers.
@@ -795,6 +968,7 @@ func (c *Context) SetCookie(name, value string, maxAge int, path, domain string,
 		MaxAge:   maxAge,
 		Path:     path,
 		Domain:   domain,
+		SameSite: c.sameSite,
 		Secure:   secure,
 		HttpOnly: httpOnly,
 	})
@@ -815,6 +989,22 @@ func (c *Context) Cookie(name string) (string, error) {
 
 
 
+// Written returns true if the response body was already written.
+func (c *Context) Written() bool {
+	return c.writermem.Written()
+}
+
+// RenderIfNotWritten calls Render only if nothing has been written to the
+// response yet. This is handy in error-handling middleware that runs after
+// the rest of the chain and doesn't know whether an earlier handler already
+// produced a response.
+func (c *Context) RenderIfNotWritten(code int, r render.Render) {
+	if c.Written() {
+		return
+	}
+	c.Render(code, r)
+}
+
 // Render writes the response headers and calls render.Render to render data.
 func (c *Context) Render(code int, r render.Render) {
 
@@ -862,12 +1052,21 @@ func (c *Context) SecureJSON(code int, obj interface{}) {
 	c.Render(code, render.SecureJSON{Prefix: c.engine.secureJsonPrefix, Data: obj})
 }
 
+// jsonpCallbackName matches a JSONP callback that's safe to splice, unquoted,
+// into the response body: letters, digits, '_', '$' and '.' (for "a.b.c"
+// style callbacks), starting with a non-digit. Anything else could break
+// out of the intended `callback(...)` wrapper and inject arbitrary script.
+var jsonpCallbackName = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$.]*$`)
+
 // JSONP serializes the given struct as JSON into the response body.
 // It add padding to response body to request data from a server residing in a different domain than the client.
 // It also sets the Content-Type as "application/javascript".
+// The callback name comes from the "callback" query parameter and is
+// restricted to identifier-safe characters; an empty or unsafe callback
+// falls back to a plain JSON response.
 func (c *Context) JSONP(code int, obj interface{}) {
 	callback := c.DefaultQuery("callback", "")
-	if callback == "" {
+	if callback == "" || !jsonpCallbackName.MatchString(callback) {
 		c.Render(code, render.JSON{Data: obj})
 		return
 	}
@@ -916,6 +1115,20 @@ func (c *Context) Redirect(code int, location string) {
 	})
 }
 
+// RedirectRelative is like Redirect, but writes location to the Location
+// header verbatim instead of letting http.Redirect resolve it against the
+// current request URL. Use it to force an absolute-path redirect (a
+// leading "/") or a purely relative one regardless of the request path
+// that triggered it, or to point at an external absolute URL.
+func (c *Context) RedirectRelative(code int, location string) {
+	c.Render(-1, render.Redirect{
+		Code:     code,
+		Location: location,
+		Request:  c.Request,
+		Relative: true,
+	})
+}
+
 // Data writes some data into the body stream and updates the HTTP code.
 func (c *Context) Data(code int, contentType string, data []byte) {
 	c.Render(code, render.Data{
@@ -935,10 +1148,68 @@ func (c *Context) DataFromReader(code int, contentLength int64, contentType stri
 }
 
 // File writes the specified file into the body stream in a efficient way.
+//
+// filepath is served as-is: it is the caller's responsibility to sanitize
+// any part of it that comes from user input, the same way it would be for
+// a direct http.ServeFile call.
 func (c *Context) File(filepath string) {
 	http.ServeFile(c.Writer, c.Request, filepath)
 }
 
+// quoteEscaper escapes the characters that would otherwise break out of the
+// quoted-string form of a Content-Disposition filename parameter.
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// FileAttachment is like File, but also sets Content-Disposition so the
+// browser downloads filename instead of trying to display it inline. An
+// ASCII filename is sent as a plain quoted filename param; anything else
+// is sent as the UTF-8 filename* param from RFC 6266 instead, since the
+// plain param can't carry non-ASCII bytes safely across browsers.
+func (c *Context) FileAttachment(filepath, filename string) {
+	if isASCII(filename) {
+		c.Writer.Header().Set("Content-Disposition", `attachment; filename="`+quoteEscaper.Replace(filename)+`"`)
+	} else {
+		c.Writer.Header().Set("Content-Disposition", `attachment; filename*=UTF-8''`+url.QueryEscape(filename))
+	}
+	http.ServeFile(c.Writer, c.Request, filepath)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+// FileFromFS writes the file at filepath, resolved against fs, into the
+// body stream. filepath is the kind of value a *filepath catch-all param
+// hands you: it's run through cleanPath first, so a request smuggling ".."
+// segments (e.g. "/static/../gin.go" routed to a "*filepath" catch-all)
+// can't walk outside of fs by the time it reaches http.FileServer.
+func (c *Context) FileFromFS(filepath string, fs http.FileSystem) {
+	defer func(old string) {
+		c.Request.URL.Path = old
+	}(c.Request.URL.Path)
+
+	c.Request.URL.Path = cleanPath(filepath)
+
+	http.FileServer(fs).ServeHTTP(c.Writer, c.Request)
+}
+
+// Push initiates an HTTP/2 server push, letting a page handler proactively
+// send assets like its CSS/JS before the browser even asks for them. On
+// HTTP/1.1 (or any ResponseWriter that doesn't implement http.Pusher) it
+// just returns http.ErrNotSupported, so callers can ignore the error and
+// fall back to a normal response.
+func (c *Context) Push(target string, opts *http.PushOptions) error {
+	if pusher := c.Writer.Pusher(); pusher != nil {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
 // SSEvent writes a Server-Sent Event into the body stream.
 func (c *Context) SSEvent(name string, message interface{}) {
 	c.Render(-1, sse.Event{
@@ -947,14 +1218,22 @@ func (c *Context) SSEvent(name string, message interface{}) {
 	})
 }
 
-// Stream sends a streaming response.
+// Stream sends a streaming response, calling step repeatedly until it
+// returns false or the client disconnects (detected via CloseNotify or
+// the request context being done).
 func (c *Context) Stream(step func(w io.Writer) bool) {
 	w := c.Writer
 	clientGone := w.CloseNotify()
+	var ctxDone <-chan struct{}
+	if c.Request != nil {
+		ctxDone = c.Request.Context().Done()
+	}
 	for {
 		select {
 		case <-clientGone:
 			return
+		case <-ctxDone:
+			return
 		default:
 			keepOpen := step(w)
 			w.Flush()
@@ -1032,18 +1311,30 @@ func (c *Context) SetAccepted(formats ...string) {
 /***** GOLANG.ORG/X/NET/CONTEXT *****/
 /************************************/
 
-// Deadline returns the time when work done on behalf of this context should be canceled. 
-// Deadline returns ok==false when no deadline is set. 
+// Deadline returns the time when work done on behalf of this context should be canceled.
+// Deadline returns ok==false when no deadline is set.
 // Successive calls to Deadline return the same results.
+//
+// It delegates to c.Request's own context, so it reflects whatever deadline
+// TimeoutMiddleware (or any other context.WithTimeout/WithDeadline caller
+// upstream) attached to the request.
 func (c *Context) Deadline() (deadline time.Time, ok bool) {
-	return
+	if c.Request == nil || c.Request.Context() == nil {
+		return
+	}
+	return c.Request.Context().Deadline()
 }
 
 // Done returns a channel that's closed when work done on behalf of this
 // context should be canceled. Done may return nil if this context can
 // never be canceled. Successive calls to Done return the same value.
+//
+// It delegates to c.Request's own context; see Deadline.
 func (c *Context) Done() <-chan struct{} {
-	return nil
+	if c.Request == nil || c.Request.Context() == nil {
+		return nil
+	}
+	return c.Request.Context().Done()
 }
 
 // Err returns a non-nil error value after Done is closed,
@@ -1052,20 +1343,34 @@ func (c *Context) Done() <-chan struct{} {
 // If Done is closed, Err returns a non-nil error explaining why:
 // Canceled if the context was canceled
 // or DeadlineExceeded if the context's deadline passed.
+//
+// It delegates to c.Request's own context; see Deadline.
 func (c *Context) Err() error {
-	return nil
+	if c.Request == nil || c.Request.Context() == nil {
+		return nil
+	}
+	return c.Request.Context().Err()
 }
 
-// Value returns the value associated with this context for key, 
-// or nil if no value is associated with key. 
+// Value returns the value associated with this context for key,
+// or nil if no value is associated with key.
 // Successive calls to Value with the same key returns the same result.
+//
+// key 0 is kept for backwards compatibility and returns c.Request. Any
+// other string key is looked up in c.Keys first, falling back to
+// c.Request's own context so values set further upstream (outside gin) are
+// still reachable through c.
 func (c *Context) Value(key interface{}) interface{} {
 	if key == 0 {
 		return c.Request
 	}
 	if keyAsString, ok := key.(string); ok {
-		val, _ := c.Get(keyAsString)
-		return val
+		if val, exists := c.Get(keyAsString); exists {
+			return val
+		}
 	}
-	return nil
+	if c.Request == nil || c.Request.Context() == nil {
+		return nil
+	}
+	return c.Request.Context().Value(key)
 }
@@ -12,6 +12,7 @@ import (
 
 // PureJSON serializes the given struct as JSON into the response body.
 // PureJSON, unlike JSON, does not replace special html characters with their unicode entities.
+// Useful for returning HTML snippets verbatim inside a JSON field to a trusted consumer.
 func (c *Context) PureJSON(code int, obj interface{}) {
 	c.Render(code, render.PureJSON{Data: obj})
 }
@@ -6,6 +6,7 @@ package gin
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
@@ -13,13 +14,16 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
 	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
@@ -580,6 +584,62 @@ func TestContextPostFormMultipart(t *testing.T) {
 	assert.Equal(t, 0, len(dicts))
 }
 
+func TestContextShouldBindWithSyncsMaxMultipartMemory(t *testing.T) {
+	c, _ := CreateTestContext(httptest.NewRecorder())
+	c.Request = createMultipartRequest()
+	c.engine.MaxMultipartMemory = 8 << 20
+
+	var obj struct {
+		Foo string `form:"foo"`
+	}
+	assert.NoError(t, c.ShouldBindWith(&obj, binding.FormMultipart))
+	assert.Equal(t, int64(8<<20), binding.MaxMemoryFromRequest(c.Request))
+}
+
+// TestContextShouldBindWithReconfiguresPerEngine ensures each Context's bind
+// carries its own Engine's MaxMultipartMemory even when two engines with
+// different limits bind concurrently. The limit now travels on the
+// Context's own c.Request (see binding.WithMaxMemory) instead of a
+// package-level var, so unlike the old shared binding.MaxMemory, one
+// engine's bind can never observe or clobber another's limit.
+func TestContextShouldBindWithReconfiguresPerEngine(t *testing.T) {
+	small := New()
+	small.MaxMultipartMemory = 1 << 20
+
+	large := New()
+	large.MaxMultipartMemory = 64 << 20
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c, _ := CreateTestContext(httptest.NewRecorder())
+			c.engine = small
+			c.Request = createMultipartRequest()
+
+			var obj struct {
+				Foo string `form:"foo"`
+			}
+			assert.NoError(t, c.ShouldBindWith(&obj, binding.FormMultipart))
+			assert.Equal(t, int64(1<<20), binding.MaxMemoryFromRequest(c.Request))
+		}()
+		go func() {
+			defer wg.Done()
+			c, _ := CreateTestContext(httptest.NewRecorder())
+			c.engine = large
+			c.Request = createMultipartRequest()
+
+			var obj struct {
+				Foo string `form:"foo"`
+			}
+			assert.NoError(t, c.ShouldBindWith(&obj, binding.FormMultipart))
+			assert.Equal(t, int64(64<<20), binding.MaxMemoryFromRequest(c.Request))
+		}()
+	}
+	wg.Wait()
+}
+
 func TestContextSetCookie(t *testing.T) {
 	c, _ := CreateTestContext(httptest.NewRecorder())
 	c.SetCookie("user", "gin", 1, "/", "localhost", true, true)
@@ -592,6 +652,13 @@ func TestContextSetCookiePathEmpty(t *testing.T) {
 	assert.Equal(t, "user=gin; Path=/; Domain=localhost; Max-Age=1; HttpOnly; Secure", c.Writer.Header().Get("Set-Cookie"))
 }
 
+func TestContextSetSameSite(t *testing.T) {
+	c, _ := CreateTestContext(httptest.NewRecorder())
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie("user", "gin", 1, "/", "localhost", true, true)
+	assert.Equal(t, "user=gin; Path=/; Domain=localhost; Max-Age=1; HttpOnly; Secure; SameSite=Strict", c.Writer.Header().Get("Set-Cookie"))
+}
+
 func TestContextGetCookie(t *testing.T) {
 	c, _ := CreateTestContext(httptest.NewRecorder())
 	c.Request, _ = http.NewRequest("GET", "/get", nil)
@@ -633,6 +700,30 @@ func TestContextRenderPanicIfErr(t *testing.T) {
 	assert.Fail(t, "Panic not detected")
 }
 
+func TestContextRenderIfNotWrittenAlreadyWritten(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+
+	c.JSON(http.StatusCreated, H{"foo": "bar"})
+	c.RenderIfNotWritten(http.StatusInternalServerError, render.String{Format: "should not appear"})
+
+	assert.True(t, c.Written())
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "{\"foo\":\"bar\"}", w.Body.String())
+}
+
+func TestContextRenderIfNotWrittenNotYetWritten(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+
+	assert.False(t, c.Written())
+	c.RenderIfNotWritten(http.StatusInternalServerError, render.String{Format: "boom"})
+
+	assert.True(t, c.Written())
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "boom", w.Body.String())
+}
+
 // Tests that the response is serialized as JSON
 // and Content-Type is set to application/json
 // and special HTML characters are escaped
@@ -675,6 +766,20 @@ func TestContextRenderJSONPWithoutCallback(t *testing.T) {
 	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
 }
 
+// Tests that an unsafe callback name falls back to a plain JSON response
+// instead of splicing it unescaped into the body.
+func TestContextRenderJSONPWithUnsafeCallback(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "http://example.com/?callback="+url.QueryEscape("alert(1)//"), nil)
+
+	c.JSONP(http.StatusCreated, H{"foo": "bar"})
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "{\"foo\":\"bar\"}", w.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+}
+
 // Tests that no JSON is rendered if code is 204
 func TestContextRenderNoContentJSON(t *testing.T) {
 	w := httptest.NewRecorder()
@@ -753,6 +858,19 @@ func TestContextRenderSecureJSON(t *testing.T) {
 	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
 }
 
+// TestContextRenderSecureJSONDefaultPrefix checks the "while(1);" fallback
+// an Engine ships with when SecureJsonPrefix is never called.
+func TestContextRenderSecureJSONDefaultPrefix(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+
+	c.SecureJSON(http.StatusOK, []string{"foo", "bar"})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "while(1);[\"foo\",\"bar\"]", w.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+}
+
 // Tests that no Custom JSON is rendered if code is 204
 func TestContextRenderNoContentSecureJSON(t *testing.T) {
 	w := httptest.NewRecorder()
@@ -798,7 +916,7 @@ func TestContextRenderHTML2(t *testing.T) {
 
 	// print debug warning log when Engine.trees > 0
 	router.addRoute("GET", "/", HandlersChain{func(_ *Context) {}})
-	assert.Len(t, router.trees, 1)
+	assert.Len(t, router.loadTrees(), 1)
 
 	templ := template.Must(template.New("t").Parse(`Hello {{.name}}`))
 	re := captureOutput(t, func() {
@@ -961,6 +1079,66 @@ func TestContextRenderFile(t *testing.T) {
 	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
 }
 
+func TestContextRenderFileFromFS(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+
+	c.Request, _ = http.NewRequest("GET", "/static/gin.go", nil)
+	c.FileFromFS("gin.go", Dir(".", false))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "func New() *Engine {")
+	assert.Equal(t, "/static/gin.go", c.Request.URL.Path)
+}
+
+func TestContextRenderFileFromFSSanitizesTraversal(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+
+	c.Request, _ = http.NewRequest("GET", "/static/../gin.go", nil)
+	// a catch-all *filepath param for "/static/../gin.go" captures
+	// "../gin.go" verbatim; FileFromFS must clean it to "/gin.go" before
+	// resolving it against fs, the same path the request ends up at
+	// rather than escaping above fs's root.
+	c.FileFromFS("../gin.go", Dir(".", false))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "func New() *Engine {")
+}
+
+func TestContextRenderFileAttachmentASCII(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	c.FileAttachment("./gin.go", "report.go")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="report.go"`, w.Header().Get("Content-Disposition"))
+}
+
+func TestContextRenderFileAttachmentUTF8(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	c.FileAttachment("./gin.go", "报告.go")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename*=UTF-8''`+url.QueryEscape("报告.go"), w.Header().Get("Content-Disposition"))
+}
+
+func TestContextRenderFileAttachmentQuotesSpecialChars(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	c.FileAttachment("./gin.go", `weird "report".go`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="weird \"report\".go"`, w.Header().Get("Content-Disposition"))
+}
+
 // TestContextRenderYAML tests that the response is serialized as YAML
 // and Content-Type is set to application/x-yaml
 func TestContextRenderYAML(t *testing.T) {
@@ -1021,7 +1199,7 @@ func TestContextRenderRedirectWithRelativePath(t *testing.T) {
 
 	c.Request, _ = http.NewRequest("POST", "http://example.com", nil)
 	assert.Panics(t, func() { c.Redirect(299, "/new_path") })
-	assert.Panics(t, func() { c.Redirect(309, "/new_path") })
+	assert.Panics(t, func() { c.Redirect(400, "/new_path") })
 
 	c.Redirect(http.StatusMovedPermanently, "/path")
 	c.Writer.WriteHeaderNow()
@@ -1059,11 +1237,23 @@ func TestContextRenderRedirectAll(t *testing.T) {
 	assert.Panics(t, func() { c.Redirect(http.StatusOK, "/resource") })
 	assert.Panics(t, func() { c.Redirect(http.StatusAccepted, "/resource") })
 	assert.Panics(t, func() { c.Redirect(299, "/resource") })
-	assert.Panics(t, func() { c.Redirect(309, "/resource") })
+	assert.Panics(t, func() { c.Redirect(400, "/resource") })
 	assert.NotPanics(t, func() { c.Redirect(http.StatusMultipleChoices, "/resource") })
-	// todo(thinkerou): go1.6 not support StatusPermanentRedirect(308)
-	// when we upgrade go version we can use http.StatusPermanentRedirect
+	// any 3xx is accepted now, not just the historical 300-308 range
 	assert.NotPanics(t, func() { c.Redirect(308, "/resource") })
+	assert.NotPanics(t, func() { c.Redirect(309, "/resource") })
+}
+
+func TestContextRenderRedirectRelative(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+
+	c.Request, _ = http.NewRequest("GET", "http://example.com/current/path", nil)
+	c.RedirectRelative(http.StatusFound, "https://other.example/elsewhere")
+	c.Writer.WriteHeaderNow()
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://other.example/elsewhere", w.Header().Get("Location"))
 }
 
 func TestContextNegotiationWithJSON(t *testing.T) {
@@ -1147,6 +1337,14 @@ func TestContextNegotiationFormatWithAccept(t *testing.T) {
 	assert.Empty(t, c.NegotiateFormat(MIMEJSON))
 }
 
+func TestContextNegotiationFormatRespectsQValueOrder(t *testing.T) {
+	c, _ := CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("POST", "/", nil)
+	c.Request.Header.Add("Accept", "application/json;q=0.9, application/xml")
+
+	assert.Equal(t, MIMEXML, c.NegotiateFormat(MIMEJSON, MIMEXML))
+}
+
 func TestContextNegotiationFormatCustom(t *testing.T) {
 	c, _ := CreateTestContext(httptest.NewRecorder())
 	c.Request, _ = http.NewRequest("POST", "/", nil)
@@ -1278,9 +1476,24 @@ func TestContextAbortWithError(t *testing.T) {
 	assert.True(t, c.IsAborted())
 }
 
+// TestContextAbortWithErrorIsRetrievableFromErrors checks that a
+// centralized error-handling middleware running after the chain has been
+// aborted can still retrieve what AbortWithError recorded via c.Errors.
+func TestContextAbortWithErrorIsRetrievableFromErrors(t *testing.T) {
+	c, _ := CreateTestContext(httptest.NewRecorder())
+	assert.Empty(t, c.Errors)
+
+	c.AbortWithError(http.StatusBadRequest, errors.New("bad input")) // nolint: errcheck
+
+	assert.Len(t, c.Errors, 1)
+	assert.Equal(t, "bad input", c.Errors[0].Err.Error())
+	assert.Equal(t, ErrorTypePrivate, c.Errors.Last().Type)
+}
+
 func TestContextClientIP(t *testing.T) {
 	c, _ := CreateTestContext(httptest.NewRecorder())
 	c.Request, _ = http.NewRequest("POST", "/", nil)
+	assert.NoError(t, c.engine.SetTrustedProxies([]string{"40.40.40.40/32", "30.30.30.30/32"}))
 
 	c.Request.Header.Set("X-Real-IP", " 10.10.10.10  ")
 	c.Request.Header.Set("X-Forwarded-For", "  20.20.20.20, 30.30.30.30")
@@ -1308,6 +1521,40 @@ func TestContextClientIP(t *testing.T) {
 	assert.Empty(t, c.ClientIP())
 }
 
+// TestContextClientIPUntrustedPeerIgnoresForwardingHeaders is the spoofing
+// case the trusted-proxies check exists for: a direct caller can set
+// X-Forwarded-For to anything, so if its own RemoteAddr isn't in a
+// configured trusted network, the header must be ignored entirely.
+func TestContextClientIPUntrustedPeerIgnoresForwardingHeaders(t *testing.T) {
+	c, _ := CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("POST", "/", nil)
+	assert.NoError(t, c.engine.SetTrustedProxies([]string{"10.0.0.0/8"}))
+
+	c.Request.Header.Set("X-Forwarded-For", "20.20.20.20")
+	c.Request.RemoteAddr = "99.99.99.99:1234"
+
+	assert.Equal(t, "99.99.99.99", c.ClientIP())
+}
+
+// TestContextClientIPWalksForwardedForToFirstUntrustedHop exercises a chain
+// where more than one hop is itself a trusted proxy: the client IP is the
+// rightmost entry that isn't one of ours, not just the leftmost entry.
+func TestContextClientIPWalksForwardedForToFirstUntrustedHop(t *testing.T) {
+	c, _ := CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("POST", "/", nil)
+	assert.NoError(t, c.engine.SetTrustedProxies([]string{"10.0.0.0/8"}))
+
+	c.Request.Header.Set("X-Forwarded-For", "1.1.1.1, 2.2.2.2, 10.0.0.1")
+	c.Request.RemoteAddr = "10.0.0.2:1234"
+
+	assert.Equal(t, "2.2.2.2", c.ClientIP())
+}
+
+func TestContextSetTrustedProxiesInvalidCIDR(t *testing.T) {
+	c, _ := CreateTestContext(httptest.NewRecorder())
+	assert.Error(t, c.engine.SetTrustedProxies([]string{"not-a-cidr"}))
+}
+
 func TestContextContentType(t *testing.T) {
 	c, _ := CreateTestContext(httptest.NewRecorder())
 	c.Request, _ = http.NewRequest("POST", "/", nil)
@@ -1401,6 +1648,46 @@ func TestContextBindWithYAML(t *testing.T) {
 	assert.Equal(t, 0, w.Body.Len())
 }
 
+func TestContextBindAndValidateSuccess(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+
+	c.Request, _ = http.NewRequest("POST", "/", bytes.NewBufferString("email=a@b.com"))
+	c.Request.Header.Add("Content-Type", MIMEPOSTForm)
+
+	var obj struct {
+		Email string `form:"email" binding:"required"`
+	}
+	assert.True(t, c.BindAndValidate(&obj))
+	assert.Equal(t, "a@b.com", obj.Email)
+	assert.Equal(t, 0, w.Body.Len())
+	assert.False(t, c.IsAborted())
+}
+
+func TestContextBindAndValidateFailure(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+
+	c.Request, _ = http.NewRequest("POST", "/", bytes.NewBufferString("name=bob"))
+	c.Request.Header.Add("Content-Type", MIMEPOSTForm)
+
+	var obj struct {
+		Email string `form:"email" binding:"required"`
+		Name  string `form:"name"`
+	}
+	assert.False(t, c.BindAndValidate(&obj))
+	c.Writer.WriteHeaderNow()
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.True(t, c.IsAborted())
+
+	var body struct {
+		Errors map[string]string `json:"errors"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Contains(t, body.Errors, "email")
+}
+
 func TestContextBadAutoBind(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := CreateTestContext(w)
@@ -1422,6 +1709,23 @@ func TestContextBadAutoBind(t *testing.T) {
 	assert.True(t, c.IsAborted())
 }
 
+func TestContextBadAutoBindRecordsBindError(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+
+	c.Request, _ = http.NewRequest("POST", "http://example.com", bytes.NewBufferString("\"foo\":\"bar\", \"bar\":\"foo\"}"))
+	c.Request.Header.Add("Content-Type", MIMEJSON)
+	var obj struct {
+		Foo string `json:"foo"`
+	}
+
+	assert.Error(t, c.Bind(&obj))
+
+	assert.Len(t, c.Errors, 1)
+	assert.True(t, c.Errors.Last().IsType(ErrorTypeBind))
+	assert.Equal(t, []string{c.Errors.Last().Error()}, c.Errors.ByType(ErrorTypeBind).Errors())
+}
+
 func TestContextAutoShouldBindJSON(t *testing.T) {
 	c, _ := CreateTestContext(httptest.NewRecorder())
 	c.Request, _ = http.NewRequest("POST", "/", bytes.NewBufferString("{\"foo\":\"bar\", \"bar\":\"foo\"}"))
@@ -1437,6 +1741,41 @@ func TestContextAutoShouldBindJSON(t *testing.T) {
 	assert.Empty(t, c.Errors)
 }
 
+func TestContextAutoShouldBindStripsCharsetParameter(t *testing.T) {
+	c, _ := CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("POST", "/", bytes.NewBufferString(`{"foo":"bar"}`))
+	c.Request.Header.Add("Content-Type", "application/json; charset=utf-8")
+
+	var obj struct {
+		Foo string `json:"foo"`
+	}
+	assert.NoError(t, c.ShouldBind(&obj))
+	assert.Equal(t, "bar", obj.Foo)
+}
+
+func TestContextAutoShouldBindGetFallsBackToQuery(t *testing.T) {
+	c, _ := CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/?foo=bar", nil)
+
+	var obj struct {
+		Foo string `form:"foo"`
+	}
+	assert.NoError(t, c.ShouldBind(&obj))
+	assert.Equal(t, "bar", obj.Foo)
+}
+
+func TestContextAutoShouldBindUnknownContentTypeFallsBackToForm(t *testing.T) {
+	c, _ := CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("POST", "/?foo=bar", bytes.NewBufferString(""))
+	c.Request.Header.Add("Content-Type", "application/octet-stream")
+
+	var obj struct {
+		Foo string `form:"foo"`
+	}
+	assert.NoError(t, c.ShouldBind(&obj))
+	assert.Equal(t, "bar", obj.Foo)
+}
+
 func TestContextShouldBindWithJSON(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := CreateTestContext(w)
@@ -1454,6 +1793,90 @@ func TestContextShouldBindWithJSON(t *testing.T) {
 	assert.Equal(t, 0, w.Body.Len())
 }
 
+// customStructValidator is a binding.StructValidator that doesn't wrap
+// validator.v8 at all, to prove ShouldBind* only ever goes through the
+// binding.Validator interface rather than assuming a particular engine.
+type customStructValidator struct {
+	called bool
+}
+
+func (v *customStructValidator) ValidateStruct(obj interface{}) error {
+	v.called = true
+	return errors.New("rejected by custom validator")
+}
+
+func (v *customStructValidator) Engine() interface{} {
+	return v
+}
+
+func TestContextShouldBindWithCustomValidator(t *testing.T) {
+	backup := binding.Validator
+	custom := &customStructValidator{}
+	binding.Validator = custom
+	defer func() { binding.Validator = backup }()
+
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+
+	c.Request, _ = http.NewRequest("POST", "/", bytes.NewBufferString("{\"foo\":\"bar\"}"))
+	c.Request.Header.Add("Content-Type", MIMEJSON)
+
+	var obj struct {
+		Foo string `json:"foo" binding:"required"`
+	}
+	err := c.ShouldBindJSON(&obj)
+	assert.Error(t, err)
+	assert.True(t, custom.called)
+}
+
+func TestContextShouldBindHeader(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+
+	c.Request, _ = http.NewRequest("POST", "/", nil)
+	c.Request.Header.Add("X-Request-Id", "abc-123")
+	c.Request.Header.Add("Accept-Language", "en-US")
+
+	var obj struct {
+		RequestID string `header:"X-Request-Id"`
+		Language  string `header:"Accept-Language"`
+	}
+	assert.NoError(t, c.ShouldBindHeader(&obj))
+	assert.Equal(t, "abc-123", obj.RequestID)
+	assert.Equal(t, "en-US", obj.Language)
+}
+
+func TestContextShouldBindUri(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+
+	c.Params = Params{{Key: "name", Value: "eon"}, {Key: "id", Value: "1"}}
+
+	var obj struct {
+		Name string `uri:"name"`
+		ID   int    `uri:"id"`
+	}
+	assert.NoError(t, c.ShouldBindUri(&obj))
+	assert.Equal(t, "eon", obj.Name)
+	assert.Equal(t, 1, obj.ID)
+}
+
+func TestContextShouldBindUriFailureDoesNotAbort(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+
+	c.Params = Params{{Key: "id", Value: "not-a-number"}}
+
+	var obj struct {
+		ID int `uri:"id" binding:"required"`
+	}
+	// unlike BindUri, ShouldBindUri reports the error without writing a
+	// response or aborting the handler chain, leaving that choice to the
+	// caller.
+	assert.Error(t, c.ShouldBindUri(&obj))
+	assert.False(t, c.IsAborted())
+}
+
 func TestContextShouldBindWithXML(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := CreateTestContext(w)
@@ -1495,6 +1918,31 @@ func TestContextShouldBindWithQuery(t *testing.T) {
 	assert.Equal(t, 0, w.Body.Len())
 }
 
+func TestContextShouldBindQueryIgnoresJSONBody(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+
+	c.Request, _ = http.NewRequest("POST", "/?page=2&limit=10", bytes.NewBufferString(`{"page":999,"limit":999}`))
+	c.Request.Header.Add("Content-Type", MIMEJSON)
+
+	var filter struct {
+		Page  int `form:"page"`
+		Limit int `form:"limit"`
+	}
+	var body struct {
+		Page  int `json:"page"`
+		Limit int `json:"limit"`
+	}
+
+	assert.NoError(t, c.ShouldBindQuery(&filter))
+	assert.Equal(t, 2, filter.Page)
+	assert.Equal(t, 10, filter.Limit)
+
+	assert.NoError(t, c.ShouldBindJSON(&body))
+	assert.Equal(t, 999, body.Page)
+	assert.Equal(t, 999, body.Limit)
+}
+
 func TestContextShouldBindWithYAML(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := CreateTestContext(w)
@@ -1610,6 +2058,35 @@ func TestContextShouldBindBodyWith(t *testing.T) {
 	}
 }
 
+func TestContextShouldBindBodyWithCacheClearedOnReset(t *testing.T) {
+	type typeA struct {
+		Foo string `json:"foo" binding:"required"`
+	}
+
+	router := New()
+	c := router.allocateContext()
+
+	w1 := httptest.NewRecorder()
+	c.writermem.reset(w1)
+	c.Request, _ = http.NewRequest("POST", "http://example.com", bytes.NewBufferString(`{"foo":"FOO"}`))
+
+	var obj1 typeA
+	assert.NoError(t, c.ShouldBindBodyWith(&obj1, binding.JSON))
+	assert.Equal(t, typeA{"FOO"}, obj1)
+
+	// a pooled Context is reused across requests after reset; the cached
+	// body from the previous request must not leak into this one.
+	c.reset()
+
+	w2 := httptest.NewRecorder()
+	c.writermem.reset(w2)
+	c.Request, _ = http.NewRequest("POST", "http://example.com", bytes.NewBufferString(`{"foo":"BAR"}`))
+
+	var obj2 typeA
+	assert.NoError(t, c.ShouldBindBodyWith(&obj2, binding.JSON))
+	assert.Equal(t, typeA{"BAR"}, obj2)
+}
+
 func TestContextGolangContext(t *testing.T) {
 	c, _ := CreateTestContext(httptest.NewRecorder())
 	c.Request, _ = http.NewRequest("POST", "/", bytes.NewBufferString("{\"foo\":\"bar\", \"bar\":\"foo\"}"))
@@ -1626,6 +2103,34 @@ func TestContextGolangContext(t *testing.T) {
 	assert.Nil(t, c.Value(1))
 }
 
+func TestContextGolangContextDelegatesToRequestContext(t *testing.T) {
+	c, _ := CreateTestContext(httptest.NewRecorder())
+	req, _ := http.NewRequest("GET", "/", nil)
+	ctx, cancel := context.WithTimeout(req.Context(), time.Hour)
+	defer cancel()
+	ctx = context.WithValue(ctx, requestScopedKey{}, "upstream")
+	c.Request = req.WithContext(ctx)
+
+	deadline, ok := c.Deadline()
+	wantDeadline, _ := ctx.Deadline()
+	assert.True(t, ok)
+	assert.Equal(t, wantDeadline, deadline)
+	assert.NotNil(t, c.Done())
+	assert.NoError(t, c.Err())
+
+	// gin's own Keys take priority over the request context.
+	c.Set("foo", "gin")
+	assert.Equal(t, "gin", c.Value("foo"))
+	// a value set only upstream on the request context is still reachable.
+	assert.Equal(t, "upstream", c.Value(requestScopedKey{}))
+
+	cancel()
+	<-c.Done()
+	assert.Equal(t, context.Canceled, c.Err())
+}
+
+type requestScopedKey struct{}
+
 func TestWebsocketsRequired(t *testing.T) {
 	// Example request from spec: https://tools.ietf.org/html/rfc6455#section-1.2
 	c, _ := CreateTestContext(httptest.NewRecorder())
@@ -1668,6 +2173,35 @@ func TestContextGetRawData(t *testing.T) {
 	assert.Equal(t, "Fetch binary post data", string(data))
 }
 
+func TestContextGetRawDataThenBindStillSucceeds(t *testing.T) {
+	c, _ := CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("POST", "/", bytes.NewBufferString(`{"foo":"FOO"}`))
+	c.Request.Header.Add("Content-Type", MIMEJSON)
+
+	raw, err := c.GetRawData()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"foo":"FOO"}`, string(raw))
+
+	var obj struct {
+		Foo string `json:"foo"`
+	}
+	assert.NoError(t, c.ShouldBindJSON(&obj))
+	assert.Equal(t, "FOO", obj.Foo)
+}
+
+func TestContextGetRawDataCachedAcrossCalls(t *testing.T) {
+	c, _ := CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("POST", "/", bytes.NewBufferString("raw body"))
+
+	first, err := c.GetRawData()
+	assert.NoError(t, err)
+	assert.Equal(t, "raw body", string(first))
+
+	second, err := c.GetRawData()
+	assert.NoError(t, err)
+	assert.Equal(t, "raw body", string(second))
+}
+
 func TestContextRenderDataFromReader(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := CreateTestContext(w)
@@ -1744,6 +2278,27 @@ func TestContextStreamWithClientGone(t *testing.T) {
 	assert.Equal(t, "test", w.Body.String())
 }
 
+func TestContextStreamWithContextCanceled(t *testing.T) {
+	w := CreateTestResponseRecorder()
+	c, _ := CreateTestContext(w)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.Request = httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+
+	iterations := 0
+	c.Stream(func(writer io.Writer) bool {
+		iterations++
+		if iterations == 1 {
+			cancel()
+		}
+		_, err := writer.Write([]byte("test"))
+		assert.NoError(t, err)
+		return true
+	})
+
+	assert.Equal(t, 1, iterations)
+}
+
 func TestContextResetInHandler(t *testing.T) {
 	w := CreateTestResponseRecorder()
 	c, _ := CreateTestContext(w)
@@ -1755,3 +2310,28 @@ func TestContextResetInHandler(t *testing.T) {
 		c.Next()
 	})
 }
+
+type TestPusherResponseRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (r *TestPusherResponseRecorder) Push(target string, opts *http.PushOptions) error {
+	r.pushed = append(r.pushed, target)
+	return nil
+}
+
+func TestContextPush(t *testing.T) {
+	recorder := &TestPusherResponseRecorder{ResponseRecorder: httptest.NewRecorder()}
+	c, _ := CreateTestContext(recorder)
+
+	assert.NoError(t, c.Push("/static/app.css", nil))
+	assert.Equal(t, []string{"/static/app.css"}, recorder.pushed)
+}
+
+func TestContextPushNotSupported(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+
+	assert.Equal(t, http.ErrNotSupported, c.Push("/static/app.css", nil))
+}
@@ -5,18 +5,32 @@
 package gin
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"html/template"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin/render"
 )
 
 const defaultMultipartMemory = 32 << 20 // 32 MB
 
+// defaultMaxRewriteDepth is the default number of times HandleContext will
+// let a context re-enter routing before refusing to recurse further.
+const defaultMaxRewriteDepth = 8
+
 var (
 	default404Body   = []byte("404 page not found")
 	default405Body   = []byte("405 method not allowed")
@@ -29,7 +43,7 @@ type HandlerFunc func(*Context)
 // HandlersChain defines a HandlerFunc array.
 type HandlersChain []HandlerFunc
 
-// Last returns the last handler in the chain. 
+// Last returns the last handler in the chain.
 // ie. the last handler is the main own.
 func (c HandlersChain) Last() HandlerFunc {
 	if length := len(c); length > 0 {
@@ -40,6 +54,7 @@ func (c HandlersChain) Last() HandlerFunc {
 
 // RouteInfo represents a request route's specification which contains method and path and its handler.
 type RouteInfo struct {
+	Host        string
 	Method      string
 	Path        string
 	Handler     string
@@ -49,11 +64,76 @@ type RouteInfo struct {
 // RoutesInfo defines a RouteInfo array.
 type RoutesInfo []RouteInfo
 
+// groupFallback pairs a RouterGroup's basePath with the NoRoute/NoMethod
+// handlers it registered, so handleHTTPRequest can pick the most specific
+// (longest matching basePath) group fallback for an unmatched request.
+type groupFallback struct {
+	prefix   string
+	handlers HandlersChain
+}
+
+// bestFallback returns the handlers of the entry whose prefix is both a
+// prefix of path and the longest among all matches, falling back to
+// deflt when none match.
+func bestFallback(entries []groupFallback, path string, deflt HandlersChain) HandlersChain {
+	best := deflt
+	bestLen := -1
+	for _, entry := range entries {
+		if len(entry.prefix) > bestLen && strings.HasPrefix(path, entry.prefix) {
+			best = entry.handlers
+			bestLen = len(entry.prefix)
+		}
+	}
+	return best
+}
+
+// groupBoolOverride pairs a RouterGroup's basePath with a per-group
+// override of an otherwise engine-wide boolean setting, so handleHTTPRequest
+// can pick the most specific (longest matching basePath) override for a
+// request, falling back to the engine default when no group along the path
+// has one.
+type groupBoolOverride struct {
+	prefix string
+	value  bool
+}
+
+// bestBoolOverride returns the value of the entry whose prefix is both a
+// prefix of path and the longest among all matches, falling back to deflt
+// when none match.
+func bestBoolOverride(entries []groupBoolOverride, path string, deflt bool) bool {
+	best := deflt
+	bestLen := -1
+	for _, entry := range entries {
+		if len(entry.prefix) > bestLen && strings.HasPrefix(path, entry.prefix) {
+			best = entry.value
+			bestLen = len(entry.prefix)
+		}
+	}
+	return best
+}
+
+// Tracer lets an Engine start an observability span around each request.
+// StartSpan is called with the matched route's full path (e.g.
+// "/users/:id") so span names stay low-cardinality, and must return the
+// (possibly wrapped) request context together with a function that ends
+// the span; end is called once the handler chain has finished.
+type Tracer interface {
+	StartSpan(ctx context.Context, name string) (context.Context, func())
+}
+
+// RequestInfo is passed to an OnRequest hook once per request, after
+// ServeHTTP's call into handleHTTPRequest has returned.
+type RequestInfo struct {
+	Method   string        // c.Request.Method
+	FullPath string        // the matched route template, e.g. "/users/:id"; empty if no route matched (404/405)
+	Status   int           // the final response status code
+	Latency  time.Duration // time spent in handleHTTPRequest
+}
+
 // Engine is the framework's instance, it contains the muxer, middleware and configuration settings.
 // Create an instance of Engine, by using New() or Default()
 type Engine struct {
 
-
 	// Engine 继承于 RouterGroup，由于“路由”和“引擎”毕竟是两个逻辑，使用继承的方式有利于代码逻辑分离。
 	RouterGroup
 
@@ -63,12 +143,34 @@ type Engine struct {
 	// client is redirected to /foo with http status code 301 for GET requests
 	// and 307 for all other request methods.
 
-    // 如果true，当前路由匹配失败但将路径最后的 / 去掉时匹配成功时	，自动匹配后者。
-    // 比如：请求是 /foo/ 但没有命中，而存在 /foo，
-    // 对 get method 请求，客户端会被301重定向到 /foo
-    // 对于其他 method 请求，客户端会被307重定向到 /foo
+	// 如果true，当前路由匹配失败但将路径最后的 / 去掉时匹配成功时	，自动匹配后者。
+	// 比如：请求是 /foo/ 但没有命中，而存在 /foo，
+	// 对 get method 请求，客户端会被301重定向到 /foo
+	// 对于其他 method 请求，客户端会被307重定向到 /foo
 	RedirectTrailingSlash bool
 
+	// RedirectMethodPreserving changes the trailing-slash redirect's status
+	// code to 308 (Permanent Redirect) regardless of request method,
+	// instead of the default 301 for GET and 307 for everything else. 308
+	// is the method-preserving counterpart of 301: browsers and HTTP
+	// clients are required to keep the original method and body on the
+	// follow-up request, which 301 never formally guaranteed. Useful when
+	// a path change is permanent but some of the affected routes take
+	// POST/PUT/PATCH, where 307 would correctly preserve the method but
+	// only advertise the move as temporary. Default off (301/307).
+	RedirectMethodPreserving bool
+
+	// RedirectTrailingSlashRewrite, instead of sending a 301/307/308 when
+	// RedirectTrailingSlash kicks in, rewrites c.Request.URL.Path to the
+	// corrected path and re-enters routing via HandleContext, serving the
+	// matched handler directly within the same response. This preserves
+	// the original method and body without a second round-trip, at the
+	// cost of the corrected path never reaching the client's address bar
+	// or being cacheable the way a real redirect would be. Has no effect
+	// unless RedirectTrailingSlash is also enabled (or overridden on for
+	// the matched group). Default off (redirect, not rewrite).
+	RedirectTrailingSlashRewrite bool
+
 	// If enabled, the router tries to fix the current request path, if no
 	// handle is registered for it.
 	// First superfluous path elements like ../ or // are removed.
@@ -79,14 +181,13 @@ type Engine struct {
 	// For example /FOO and /..//Foo could be redirected to /foo.
 	// RedirectTrailingSlash is independent of this option.
 
-
- 	// 如果true，在没有handler被注册来处理当前请求时，router将尝试修复当前请求路径
-    // 逻辑为：
-    // 		1. 移除前面的 ../ 或者 //
-    //  	2. 对新的路径进行大小写不敏感的查询
-    // 如果找到了handler，请求会被301或307重定向
-    // 比如： /FOO 和 /..//FOO 会被重定向到 /foo
-    // 备注：RedirectTrailingSlash 参数和这个参数独立
+	// 如果true，在没有handler被注册来处理当前请求时，router将尝试修复当前请求路径
+	// 逻辑为：
+	// 		1. 移除前面的 ../ 或者 //
+	//  	2. 对新的路径进行大小写不敏感的查询
+	// 如果找到了handler，请求会被301或307重定向
+	// 比如： /FOO 和 /..//FOO 会被重定向到 /foo
+	// 备注：RedirectTrailingSlash 参数和这个参数独立
 	RedirectFixedPath bool
 
 	// If enabled, the router checks if another method is allowed for the
@@ -96,7 +197,22 @@ type Engine struct {
 	// If no other Method is allowed, the request is delegated to the NotFound
 	// handler.
 	HandleMethodNotAllowed bool
-	ForwardedByClientIP    bool
+
+	// ForwardedByClientIP, when true, lets Context.ClientIP() trust the
+	// X-Forwarded-For/X-Real-Ip headers instead of Request.RemoteAddr,
+	// but only for requests whose immediate peer is in trustedProxies
+	// (configured via SetTrustedProxies). With no trusted proxies
+	// configured, the headers are never honored, since RemoteAddr is the
+	// only hop ClientIP() can actually verify.
+	ForwardedByClientIP bool
+
+	// HandleOPTIONS, when true, auto-replies 204 to an OPTIONS request
+	// that matches a registered path under a different method, setting
+	// the Allow header to the methods registered for that path. This
+	// spares callers from registering OPTIONS on every route just to
+	// satisfy CORS preflight checks. HandleMethodNotAllowed gets this same
+	// OPTIONS behavior for free, since it already needs the Allow header.
+	HandleOPTIONS bool
 
 	// #726 #755 If enabled, it will thrust some headers starting with
 	// 'X-AppEngine...' for better integration with that PaaS.
@@ -110,34 +226,77 @@ type Engine struct {
 	// as url.Path gonna be used, which is already unescaped.
 	UnescapePathValues bool
 
+	// If enabled, a ";key=value" matrix parameter suffix on any path segment
+	// (e.g. "/foo;jsessionid=1234/bar") is stripped before routing, so the
+	// request matches as if it had been "/foo/bar". Default off.
+	RemoveMatrixParams bool
+
 	// Value of 'maxMemory' param that is given to http.Request's ParseMultipartForm
 	// method call.
 	MaxMultipartMemory int64
 
-	delims           render.Delims
-	secureJsonPrefix string
-	HTMLRender       render.HTMLRender
-	FuncMap          template.FuncMap
-	allNoRoute       HandlersChain
-	allNoMethod      HandlersChain
-	noRoute          HandlersChain
-	noMethod         HandlersChain
-	pool             sync.Pool
-	trees            methodTrees
+	// MaxRewriteDepth caps how many times HandleContext may re-enter routing
+	// for the same context (e.g. a handler that rewrites c.Request.URL.Path
+	// and calls HandleContext again). Once exceeded, HandleContext aborts the
+	// request with a 500 instead of recursing further. Defaults to 8.
+	MaxRewriteDepth int
+
+	// MaxRequestBodySize, when greater than zero, wraps every incoming
+	// request body in an http.MaxBytesReader before handlers run. A body
+	// that reads past the limit fails with an *http.MaxBytesError, which the
+	// Bind family of methods surfaces as a 413 instead of the usual 400.
+	// Unlike MaxMultipartMemory, which only governs in-memory buffering of
+	// multipart forms, this bounds the raw body for every request. Zero (the
+	// default) means unlimited.
+	MaxRequestBodySize int64
+
+	// If enabled, the default 404/405 responses are rendered as
+	// {"code":<status>,"message":<text>} JSON bodies instead of plain text,
+	// without requiring a custom NoRoute/NoMethod handler. Default off.
+	APIErrorMode bool
+
+	// Tracer, when set, wraps each matched request in an observability
+	// span named after the route's full path. Left nil (the default) it
+	// is a no-op: handleHTTPRequest skips straight to c.Next().
+	Tracer Tracer
+
+	// onRequest, when set via OnRequest, is called once per request after
+	// handleHTTPRequest returns, for lightweight instrumentation that
+	// doesn't want to be installed as middleware.
+	onRequest func(RequestInfo)
+
+	custom404ContentType string
+	custom404Body        []byte
+	custom405ContentType string
+	custom405Body        []byte
+
+	delims             render.Delims
+	secureJsonPrefix   string
+	statusRewriter     func(code int) int
+	HTMLRender         render.HTMLRender
+	FuncMap            template.FuncMap
+	allNoRoute         HandlersChain
+	allNoMethod        HandlersChain
+	groupNoRoute       []groupFallback
+	groupNoMethod      []groupFallback
+	groupTrailingSlash []groupBoolOverride
+	noRoute            HandlersChain
+	noMethod           HandlersChain
+	pool               sync.Pool
+	treesVal           atomic.Value // holds methodTrees, published by addRoute/RemoveRoute
+	treesMu            sync.Mutex   // serializes writers; handleHTTPRequest never takes it
+	hostTreesVal       atomic.Value // holds map[string]methodTrees, published by addRouteForHost
+	namedRoutes        map[string]string
+	lastRoutePath      string
+	trustedProxies     []*net.IPNet
 }
 
 var _ IRouter = &Engine{}
 
-
-
-
-
 //初始化：
 // 1. gin.New() 初始化得到一个 *gin.Engine, 这个 Engine 是不带任何 middleware 的;
 // 2. gin.Default() 初始化会在空的 Engine 加上了 Logger 和 Recovery 这俩 middleware。
 
-
-
 // New returns a new blank Engine instance without any middleware attached.
 // By default the configuration is:
 // - RedirectTrailingSlash:  true
@@ -146,6 +305,8 @@ var _ IRouter = &Engine{}
 // - ForwardedByClientIP:    true
 // - UseRawPath:             false
 // - UnescapePathValues:     true
+// - RemoveMatrixParams:     false
+// - RedirectMethodPreserving: false
 func New() *Engine {
 	debugPrintWARNINGNew()
 	engine := &Engine{
@@ -163,19 +324,23 @@ func New() *Engine {
 		UseRawPath:             false,
 		UnescapePathValues:     true,
 		MaxMultipartMemory:     defaultMultipartMemory,
-		// trees 是一个多维切片，每个请求方法(Get/Post/Put/...)都有对应的 methodTree
-		trees:                  make(methodTrees, 0, 9),
+		MaxRewriteDepth:        defaultMaxRewriteDepth,
 		delims:                 render.Delims{Left: "{{", Right: "}}"},
 		secureJsonPrefix:       "while(1);",
+		namedRoutes:            make(map[string]string),
 	}
 
 	//反向关系保存到RouterGroup中
 	engine.RouterGroup.engine = engine
 
+	// trees 是一个多维切片，每个请求方法(Get/Post/Put/...)都有对应的 methodTree，
+	// 发布到 treesVal 以便 handleHTTPRequest 可以无锁读取
+	engine.storeTrees(make(methodTrees, 0, 9))
+
 	// engine.pool 使用了 go 标准库里的 sync/pool，实现了 Gin Context 的临时对象池，
 	// 便于重用 Context，以减少 golang gc 垃圾回收的压力，提升框架性能。
 	engine.pool.New = func() interface{} {
-		 // 分配新context
+		// 分配新context
 		return engine.allocateContext()
 	}
 	return engine
@@ -191,7 +356,6 @@ func Default() *Engine {
 	return engine
 }
 
-
 func (engine *Engine) allocateContext() *Context {
 	return &Context{
 		engine: engine,
@@ -210,6 +374,64 @@ func (engine *Engine) SecureJsonPrefix(prefix string) *Engine {
 	return engine
 }
 
+// OnRequest registers fn to be called once per request, right after
+// ServeHTTP's call into handleHTTPRequest returns, with the method, matched
+// route template, final status code and latency. It fires even when no
+// route matched (FullPath left empty), which makes it a cheaper single
+// integration point for a tracing/metrics exporter than installing a
+// middleware that has to wrap every handler chain. Only one hook can be
+// registered; a later call replaces the previous one. Passing nil clears it.
+func (engine *Engine) OnRequest(fn func(RequestInfo)) {
+	engine.onRequest = fn
+}
+
+// SetTrustedProxies configures the set of CIDRs that Context.ClientIP()
+// trusts to have set X-Forwarded-For/X-Real-Ip truthfully. A request is
+// only allowed to override its client IP via those headers when its
+// RemoteAddr falls inside one of these networks; everything else gets its
+// literal RemoteAddr back, since an untrusted peer could set the headers
+// to anything. Passing nil or an empty slice clears the trusted set,
+// falling back to RemoteAddr regardless of ForwardedByClientIP. Returns an
+// error without changing the configured set if any cidr fails to parse.
+func (engine *Engine) SetTrustedProxies(cidrs []string) error {
+	proxies := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			cidr += "/32"
+			if strings.Contains(cidr, ":") {
+				cidr = strings.TrimSuffix(cidr, "/32") + "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %v", cidr, err)
+		}
+		proxies = append(proxies, ipNet)
+	}
+	engine.trustedProxies = proxies
+	return nil
+}
+
+// isTrustedProxy reports whether ip, the immediate peer address of a
+// request, falls inside a network configured via SetTrustedProxies.
+func (engine *Engine) isTrustedProxy(ip net.IP) bool {
+	for _, trusted := range engine.trustedProxies {
+		if trusted.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusRewriter installs a hook that transforms the response status code
+// right before it's committed to the wire, e.g. to remap 204 to 200 for a
+// legacy client. A zero or negative return value from rewrite leaves the
+// status untouched. Pass nil to disable rewriting (the default).
+func (engine *Engine) StatusRewriter(rewrite func(code int) int) *Engine {
+	engine.statusRewriter = rewrite
+	return engine
+}
+
 // LoadHTMLGlob loads HTML files identified by glob pattern
 // and associates the result with HTML renderer.
 func (engine *Engine) LoadHTMLGlob(pattern string) {
@@ -226,6 +448,68 @@ func (engine *Engine) LoadHTMLGlob(pattern string) {
 	engine.SetHTMLTemplate(templ)
 }
 
+// LoadHTMLGlobWatch behaves like LoadHTMLGlob, but keeps watching pattern's
+// matching files for modification-time changes every interval and
+// atomically swaps in a freshly parsed template set whenever one changed,
+// so templates (e.g. for outgoing email/HTML) can be edited on disk and
+// picked up without a process restart. It returns a stop function that ends
+// the polling goroutine; callers that load templates once at startup and
+// run for the life of the process can discard it.
+func (engine *Engine) LoadHTMLGlobWatch(pattern string, interval time.Duration) (stop func()) {
+	left := engine.delims.Left
+	right := engine.delims.Right
+
+	parse := func() *template.Template {
+		return template.Must(template.New("").Delims(left, right).Funcs(engine.FuncMap).ParseGlob(pattern))
+	}
+
+	watch := &render.HTMLTemplateWatch{}
+	watch.Store(parse())
+	engine.HTMLRender = watch
+
+	lastMod := globModTime(pattern)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if mod := globModTime(pattern); mod.After(lastMod) {
+					lastMod = mod
+					watch.Store(parse())
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// globModTime returns the most recent modification time among the files
+// matched by pattern, or the zero Time if the pattern matches nothing or is
+// invalid.
+func globModTime(pattern string) time.Time {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return time.Time{}
+	}
+
+	var latest time.Time
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		if mod := info.ModTime(); mod.After(latest) {
+			latest = mod
+		}
+	}
+	return latest
+}
+
 // LoadHTMLFiles loads a slice of HTML files
 // and associates the result with HTML renderer.
 func (engine *Engine) LoadHTMLFiles(files ...string) {
@@ -238,9 +522,61 @@ func (engine *Engine) LoadHTMLFiles(files ...string) {
 	engine.SetHTMLTemplate(templ)
 }
 
+// loadTrees returns the currently published methodTrees snapshot. It's the
+// only way handleHTTPRequest and friends read engine.trees, so a route
+// registered concurrently with traffic (e.g. by a control-plane goroutine
+// calling addRoute after Run has started) is never observed half-built:
+// readers either see the snapshot from before the write or the one
+// published atomically after it, never a tree being mutated in place.
+func (engine *Engine) loadTrees() methodTrees {
+	if v, ok := engine.treesVal.Load().(methodTrees); ok {
+		return v
+	}
+	return nil
+}
+
+// storeTrees publishes trees as the new methodTrees snapshot read by
+// loadTrees. Callers that mutate the tree (addRoute, RemoveRoute) must
+// build trees from a clone of whatever loadTrees last returned rather than
+// mutating it in place, and must hold treesMu while doing so to serialize
+// against other writers.
+func (engine *Engine) storeTrees(trees methodTrees) {
+	engine.treesVal.Store(trees)
+}
+
+// loadHostTrees returns the currently published host-scoped trees snapshot,
+// the same way loadTrees does for the default (non-host) trees. It's the
+// only way hostTreesFor and Routes read engine.hostTreesVal, so a host route
+// registered concurrently with traffic is never observed half-built.
+func (engine *Engine) loadHostTrees() map[string]methodTrees {
+	if v, ok := engine.hostTreesVal.Load().(map[string]methodTrees); ok {
+		return v
+	}
+	return nil
+}
+
+// storeHostTrees publishes trees as the new host-scoped trees snapshot read
+// by loadHostTrees. Callers must build trees from a clone of whatever
+// loadHostTrees last returned rather than mutating it in place, and must
+// hold treesMu while doing so, same as storeTrees.
+func (engine *Engine) storeHostTrees(trees map[string]methodTrees) {
+	engine.hostTreesVal.Store(trees)
+}
+
+// cloneHostTrees shallow-copies m so a writer can install an updated entry
+// for one host without mutating the map a concurrent reader might be
+// ranging over.
+func cloneHostTrees(m map[string]methodTrees) map[string]methodTrees {
+	clone := make(map[string]methodTrees, len(m)+1)
+	for host, trees := range m {
+		clone[host] = trees
+	}
+	return clone
+}
+
 // SetHTMLTemplate associate a template with HTML renderer.
 func (engine *Engine) SetHTMLTemplate(templ *template.Template) {
-	if len(engine.trees) > 0 {
+	if len(engine.loadTrees()) > 0 {
 		debugPrintWARNINGSetHTMLTemplate()
 	}
 
@@ -252,6 +588,60 @@ func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
 	engine.FuncMap = funcMap
 }
 
+// RenderHTMLString renders the named template against data exactly as the
+// live HTML render path would -- same HTMLRender (so HTMLDebug still
+// reparses on every call), same FuncMap and delims -- but into an in-memory
+// buffer instead of a ResponseWriter, for callers that want to cache the
+// result (e.g. an edge cache) rather than serve it immediately. LoadHTMLGlob,
+// LoadHTMLFiles or SetHTMLTemplate must be called first; a template execution
+// error is returned rather than panicked.
+func (engine *Engine) RenderHTMLString(name string, data interface{}) (string, error) {
+	if engine.HTMLRender == nil {
+		return "", errors.New("gin: no HTML renderer registered, call LoadHTMLGlob/LoadHTMLFiles/SetHTMLTemplate first")
+	}
+
+	// render.HTML.Render wants an http.ResponseWriter to also set the
+	// Content-Type header, which a bytes.Buffer doesn't implement and which
+	// a cached string has no use for anyway -- so the template is executed
+	// directly here instead, the same way render.HTML.Render does it.
+	html, ok := engine.HTMLRender.Instance(name, data).(render.HTML)
+	if !ok {
+		return "", errors.New("gin: HTML renderer did not produce an HTML instance")
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if html.Name == "" {
+		err = html.Template.Execute(&buf, html.Data)
+	} else {
+		err = html.Template.ExecuteTemplate(&buf, html.Name, html.Data)
+	}
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// SetDefault404 configures the Content-Type and body written for the
+// built-in 404 response, i.e. when no route matches and no NoRoute handler
+// (or a NoRoute handler that doesn't write a response) applies. This is
+// simpler than registering a NoRoute handler for teams that just want a
+// consistent error envelope (e.g. JSON) across every miss. It takes
+// precedence over APIErrorMode. Passing a nil body reverts to the built-in
+// response: plain text, or the JSON body when APIErrorMode is set.
+func (engine *Engine) SetDefault404(contentType string, body []byte) {
+	engine.custom404ContentType = contentType
+	engine.custom404Body = body
+}
+
+// SetDefault405 is the HandleMethodNotAllowed equivalent of SetDefault404,
+// configuring the Content-Type and body written for the built-in 405
+// response.
+func (engine *Engine) SetDefault405(contentType string, body []byte) {
+	engine.custom405ContentType = contentType
+	engine.custom405Body = body
+}
+
 // NoRoute adds handlers for NoRoute. It return a 404 code by default.
 func (engine *Engine) NoRoute(handlers ...HandlerFunc) {
 	engine.noRoute = handlers
@@ -264,8 +654,8 @@ func (engine *Engine) NoMethod(handlers ...HandlerFunc) {
 	engine.rebuild405Handlers()
 }
 
-// Use attachs a global middleware to the router. 
-// ie. the middleware attached though Use() will be included in the handlers chain 
+// Use attachs a global middleware to the router.
+// ie. the middleware attached though Use() will be included in the handlers chain
 // for every single request. Even 404, 405, static files...
 // For example, this is the right place for a logger or error management middleware.
 
@@ -288,42 +678,323 @@ func (engine *Engine) rebuild405Handlers() {
 	engine.allNoMethod = engine.combineHandlers(engine.noMethod)
 }
 
+// splitOptionalTrailingParam looks for a path whose final segment is an
+// optional param (":name?", the "?" being the very last character of the
+// whole path) and, if found, returns the two concrete paths that together
+// match it: withParam has the segment as an ordinary required param,
+// withoutParam drops the segment (and its leading slash) entirely. ok is
+// false when path has no such trailing "?" to expand, in which case
+// withParam/withoutParam are unset and registration proceeds as normal.
+//
+// Expanding at registration time keeps getValue's O(path) walk unchanged:
+// the two variants end up as two ordinary routes in the tree, so a request
+// for either "/articles/2024" or "/articles/2024/03" is a normal lookup,
+// and a handler reading the optional param via c.Param sees "" on the
+// shorter route for free, since Params.ByName already returns "" for a
+// name it doesn't have.
+func splitOptionalTrailingParam(path string) (withParam, withoutParam string, ok bool) {
+	if len(path) == 0 || path[len(path)-1] != '?' {
+		return "", "", false
+	}
+
+	lastSlash := strings.LastIndexByte(path, '/')
+	segment := path[lastSlash+1:]
+	if len(segment) < 2 || segment[0] != ':' {
+		panic("gin: optional '?' must follow a ':param' segment, got '" + path + "'")
+	}
+
+	withParam = path[:lastSlash] + "/" + segment[:len(segment)-1]
+	if lastSlash == 0 {
+		withoutParam = "/"
+	} else {
+		withoutParam = path[:lastSlash]
+	}
+	return withParam, withoutParam, true
+}
 
 // 添加router信息
+//
+// Registration is safe to call concurrently with traffic being served by
+// handleHTTPRequest: writers serialize on treesMu and always mutate a
+// private clone of the target method's tree (node.addRoute itself is still
+// "not concurrency-safe" against a shared tree), then publish the new
+// methodTrees slice with a single atomic store. A reader that's mid-request
+// keeps using whatever snapshot it already loaded; the next request to call
+// loadTrees sees the update.
 func (engine *Engine) addRoute(method, path string, handlers HandlersChain) {
+	if withParam, withoutParam, ok := splitOptionalTrailingParam(path); ok {
+		engine.addRoute(method, withParam, handlers)
+		engine.addRoute(method, withoutParam, handlers)
+		return
+	}
+
 	// 常规检查
 	assert1(path[0] == '/', "path must begin with '/'")
 	assert1(method != "", "HTTP method can not be empty")
 	assert1(len(handlers) > 0, "there must be at least one handler")
 	debugPrintRoute(method, path, handlers)
 
-	// 维护engine.trees
-	root := engine.trees.get(method)
+	engine.treesMu.Lock()
+	defer engine.treesMu.Unlock()
+
+	trees := engine.loadTrees()
+	root := trees.get(method)
+
+	var newTrees methodTrees
+	if root == nil {
+		newRoot := new(node)
+		newRoot.addRoute(path, handlers)
+		newTrees = append(make(methodTrees, 0, len(trees)+1), trees...)
+		newTrees = append(newTrees, methodTree{method: method, root: newRoot})
+	} else {
+		clonedRoot := cloneNode(root)
+		clonedRoot.addRoute(path, handlers)
+		newTrees = make(methodTrees, len(trees))
+		copy(newTrees, trees)
+		for i := range newTrees {
+			if newTrees[i].method == method {
+				newTrees[i].root = clonedRoot
+				break
+			}
+		}
+	}
+
+	engine.storeTrees(newTrees)
+	engine.lastRoutePath = path
+}
+
+// AddRouteSafe registers a route like Handle does, but returns a conflict
+// (duplicate handler, wildcard clash, ...) as an error instead of panicking,
+// so a caller that registers routes it doesn't fully control -- a plugin
+// loader, say -- can skip the offending one and keep going. The returned
+// error's message is exactly the string the panicking path would have used,
+// so it's recognizable either way.
+//
+// Ordinary route registration (Handle, GET, POST, ...) keeps panicking:
+// a conflict there is a programming mistake that should fail loudly and
+// immediately, not be silently swallowed.
+func (engine *Engine) AddRouteSafe(method, path string, handlers HandlersChain) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	engine.addRoute(method, path, handlers)
+	return nil
+}
+
+// cloneNode deep-copies n and its entire subtree so addRoute/removeRoute can
+// mutate the copy in place (node.addRoute and node.removeRoute are not
+// concurrency-safe) while any in-flight request still walking the
+// previously published tree keeps seeing the unmodified original.
+func cloneNode(n *node) *node {
+	if n == nil {
+		return nil
+	}
+	clone := *n
+	if n.children != nil {
+		clone.children = make([]*node, len(n.children))
+		for i, child := range n.children {
+			clone.children[i] = cloneNode(child)
+		}
+	}
+	if n.handlers != nil {
+		clone.handlers = append(HandlersChain(nil), n.handlers...)
+	}
+	return &clone
+}
+
+// addRouteForHost is like addRoute but files the route under host's own set
+// of method trees instead of the default ones, when host is non-empty.
+// host-scoped trees are consulted first by handleHTTPRequest; a request
+// whose Host header doesn't match any registered host falls back to the
+// routes registered directly on the Engine (host == "").
+//
+// Like addRoute, it's safe to call concurrently with traffic: writers
+// serialize on treesMu and always mutate a private clone of the host's
+// target method tree and a shallow copy of the host map, then publish the
+// new map with a single atomic store.
+func (engine *Engine) addRouteForHost(host, method, path string, handlers HandlersChain) {
+	if withParam, withoutParam, ok := splitOptionalTrailingParam(path); ok {
+		engine.addRouteForHost(host, method, withParam, handlers)
+		engine.addRouteForHost(host, method, withoutParam, handlers)
+		return
+	}
+
+	if host == "" {
+		engine.addRoute(method, path, handlers)
+		return
+	}
+
+	assert1(path[0] == '/', "path must begin with '/'")
+	assert1(method != "", "HTTP method can not be empty")
+	assert1(len(handlers) > 0, "there must be at least one handler")
+	debugPrintRoute(method, path, handlers)
+
+	engine.treesMu.Lock()
+	defer engine.treesMu.Unlock()
+
+	hostTrees := engine.loadHostTrees()
+	trees := hostTrees[host]
+	root := trees.get(method)
+
+	var newTreesForHost methodTrees
 	if root == nil {
-		root = new(node)
-		engine.trees = append(engine.trees, methodTree{method: method, root: root})
+		newRoot := new(node)
+		newRoot.addRoute(path, handlers)
+		newTreesForHost = append(make(methodTrees, 0, len(trees)+1), trees...)
+		newTreesForHost = append(newTreesForHost, methodTree{method: method, root: newRoot})
+	} else {
+		clonedRoot := cloneNode(root)
+		clonedRoot.addRoute(path, handlers)
+		newTreesForHost = make(methodTrees, len(trees))
+		copy(newTreesForHost, trees)
+		for i := range newTreesForHost {
+			if newTreesForHost[i].method == method {
+				newTreesForHost[i].root = clonedRoot
+				break
+			}
+		}
+	}
+
+	newHostTrees := cloneHostTrees(hostTrees)
+	newHostTrees[host] = newTreesForHost
+	engine.storeHostTrees(newHostTrees)
+
+	engine.lastRoutePath = path
+}
+
+// Host returns a RouterGroup whose routes only match requests whose Host
+// header equals host, e.g. engine.Host("api.example.com").GET("/ping", ...).
+// host may also be a single-level wildcard such as "*.example.com", in
+// which case the matched label is bound to the "subdomain" param. A
+// request whose Host doesn't match any registered host falls through to
+// the routes registered directly on the Engine.
+func (engine *Engine) Host(host string) *RouterGroup {
+	assert1(host != "", "host can not be empty")
+	return &RouterGroup{
+		basePath: "/",
+		engine:   engine,
+		host:     host,
 	}
+}
 
-	// 核心，后面一起来讲
-	root.addRoute(path, handlers)
+// Name associates a name with the route that was just registered (the last
+// call to GET/POST/Handle/... on this router), so it can later be resolved
+// back into a concrete URL with Engine.URL. It panics if no route has been
+// registered yet.
+func (group *RouterGroup) Name(name string) IRoutes {
+	assert1(group.engine.lastRoutePath != "", "Name must be called right after registering a route")
+	group.engine.namedRoutes[name] = group.engine.lastRoutePath
+	return group.returnObj()
 }
 
+// URL builds a concrete URL for the named route, substituting params into
+// its path template (e.g. "/users/:id" + {"id": "42"} -> "/users/42").
+// It returns an error when the name is unknown or a required param is
+// missing. Param values are URL-escaped, except for catch-all segments
+// (e.g. "*filepath"), which may legitimately contain slashes.
+func (engine *Engine) URL(name string, params map[string]string) (string, error) {
+	tmpl, ok := engine.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("gin: no route named %q", name)
+	}
+
+	segments := strings.Split(tmpl, "/")
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		switch segment[0] {
+		case ':':
+			key := segment[1:]
+			value, ok := params[key]
+			if !ok {
+				return "", fmt.Errorf("gin: missing param %q for route %q", key, name)
+			}
+			segments[i] = url.PathEscape(value)
+		case '*':
+			key := segment[1:]
+			value, ok := params[key]
+			if !ok {
+				return "", fmt.Errorf("gin: missing param %q for route %q", key, name)
+			}
+			segments[i] = strings.TrimPrefix(value, "/")
+		}
+	}
+	return strings.Join(segments, "/"), nil
+}
 
+// RemoveRoute deregisters the handlers registered for method and path
+// (path must be the literal route as it was registered, e.g. "/users/:id",
+// not a concrete matched URL). It returns false if the route was never
+// registered.
+//
+// Like addRoute, it's safe to call while the engine is serving traffic: it
+// mutates a clone of the method's tree under treesMu and publishes the
+// result atomically.
+func (engine *Engine) RemoveRoute(method, path string) bool {
+	engine.treesMu.Lock()
+	defer engine.treesMu.Unlock()
+
+	trees := engine.loadTrees()
+	root := trees.get(method)
+	if root == nil {
+		return false
+	}
+
+	clonedRoot := cloneNode(root)
+	removed := clonedRoot.removeRoute(path)
+	if !removed {
+		return false
+	}
+
+	newTrees := make(methodTrees, len(trees))
+	copy(newTrees, trees)
+	for i := range newTrees {
+		if newTrees[i].method == method {
+			newTrees[i].root = clonedRoot
+			break
+		}
+	}
+	engine.storeTrees(newTrees)
+	return true
+}
 
 // Routes returns a slice of registered routes, including some useful information, such as:
 // the http method, path and the handler name.
+//
+// The returned slice is sorted by method, then path, then host, so the
+// ordering is stable across calls regardless of the trees' internal
+// priority order -- handy for diffing against a previous snapshot or
+// generating documentation.
 func (engine *Engine) Routes() (routes RoutesInfo) {
-	for _, tree := range engine.trees {
-		routes = iterate("", tree.method, routes, tree.root)
+	for _, tree := range engine.loadTrees() {
+		routes = iterate("", "", tree.method, routes, tree.root)
+	}
+	for host, trees := range engine.loadHostTrees() {
+		for _, tree := range trees {
+			routes = iterate("", host, tree.method, routes, tree.root)
+		}
 	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Method != routes[j].Method {
+			return routes[i].Method < routes[j].Method
+		}
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Host < routes[j].Host
+	})
 	return routes
 }
 
-func iterate(path, method string, routes RoutesInfo, root *node) RoutesInfo {
+func iterate(path, host, method string, routes RoutesInfo, root *node) RoutesInfo {
 	path += root.path
 	if len(root.handlers) > 0 {
 		handlerFunc := root.handlers.Last()
 		routes = append(routes, RouteInfo{
+			Host:        host,
 			Method:      method,
 			Path:        path,
 			Handler:     nameOfFunction(handlerFunc),
@@ -331,30 +1002,24 @@ func iterate(path, method string, routes RoutesInfo, root *node) RoutesInfo {
 		})
 	}
 	for _, child := range root.children {
-		routes = iterate(path, method, routes, child)
+		routes = iterate(path, host, method, routes, child)
 	}
 	return routes
 }
 
-
-
-
-
 // Run attaches the router to a http.Server and starts listening and serving HTTP requests.
 // It is a shortcut for http.ListenAndServe(addr, router)
 // Note: this method will block the calling goroutine indefinitely unless an error happens.
 
-
 // 执行主逻辑
 func (engine *Engine) Run(addr ...string) (err error) {
 	defer func() { debugPrintError(err) }()
 	address := resolveAddress(addr)
 	debugPrint("Listening and serving HTTP on %s\n", address)
 
-
 	//注意，这里engine需要实现 Handler 接口（https://golang.org/pkg/net/http/#Handler）：
 	// type Handler interface {
-    //     ServeHTTP(ResponseWriter, *Request)
+	//     ServeHTTP(ResponseWriter, *Request)
 	// }
 	//
 	//ServeHTTP的方法传递的两个参数，一个是Request，一个是ResponseWriter，
@@ -367,10 +1032,27 @@ func (engine *Engine) Run(addr ...string) (err error) {
 // It is a shortcut for http.ListenAndServeTLS(addr, certFile, keyFile, router)
 // Note: this method will block the calling goroutine indefinitely unless an error happens.
 func (engine *Engine) RunTLS(addr, certFile, keyFile string) (err error) {
+	defer func() { debugPrintError(err) }()
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	return engine.RunTLSConfig(addr, &tls.Config{Certificates: []tls.Certificate{cert}})
+}
+
+// RunTLSConfig attaches the router to a http.Server built with cfg and
+// starts listening and serving HTTPS requests. Unlike RunTLS, the caller
+// controls the full tls.Config (minimum TLS version, cipher suites,
+// in-memory certificates from a secret manager, etc.) instead of loading
+// a certificate from disk.
+// Note: this method will block the calling goroutine indefinitely unless an error happens.
+func (engine *Engine) RunTLSConfig(addr string, cfg *tls.Config) (err error) {
 	debugPrint("Listening and serving HTTPS on %s\n", addr)
 	defer func() { debugPrintError(err) }()
 
-	err = http.ListenAndServeTLS(addr, certFile, keyFile, engine)
+	server := &http.Server{Addr: addr, Handler: engine, TLSConfig: cfg}
+	err = server.ListenAndServeTLS("", "")
 	return
 }
 
@@ -409,8 +1091,6 @@ func (engine *Engine) RunFd(fd int) (err error) {
 	return
 }
 
-
-
 // ServeHTTP conforms to the http.Handler interface.
 
 // 1. 从 engine 的 Context pool 里取出一个 Context
@@ -424,10 +1104,27 @@ func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	c := engine.pool.Get().(*Context)
 	// 初始化上下文对象，因为从对象池取出来的数据，有脏数据，故要初始化。
 	c.writermem.reset(w)
+	c.writermem.statusRewriter = engine.statusRewriter
+	if engine.MaxRequestBodySize > 0 && req.Body != nil {
+		req.Body = http.MaxBytesReader(w, req.Body, engine.MaxRequestBodySize)
+	}
 	c.Request = req
 	c.reset()
-	// 通过请求 method 找到 engine.trees 中对应的树，然后在树中查找对应的路由, 执行相关的 handlers。
-	engine.handleHTTPRequest(c)
+
+	if engine.onRequest != nil {
+		start := time.Now()
+		// 通过请求 method 找到 engine.trees 中对应的树，然后在树中查找对应的路由, 执行相关的 handlers。
+		engine.handleHTTPRequest(c)
+		engine.onRequest(RequestInfo{
+			Method:   req.Method,
+			FullPath: c.fullPath,
+			Status:   c.writermem.Status(),
+			Latency:  time.Since(start),
+		})
+	} else {
+		// 通过请求 method 找到 engine.trees 中对应的树，然后在树中查找对应的路由, 执行相关的 handlers。
+		engine.handleHTTPRequest(c)
+	}
 	// 将Context对象扔回对象池了
 	engine.pool.Put(c)
 }
@@ -435,16 +1132,27 @@ func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 // HandleContext re-enter a context that has been rewritten.
 // This can be done by setting c.Request.URL.Path to your new target.
 // Disclaimer: You can loop yourself to death with this, use wisely.
+//
+// Each re-entry bumps c.rewriteDepth, which is reset to 0 whenever a fresh
+// request is pulled from the context pool. Once it exceeds MaxRewriteDepth,
+// HandleContext refuses to recurse further and aborts with a 500 instead of
+// looping to death on a misconfigured rewrite rule.
 func (engine *Engine) HandleContext(c *Context) {
 	oldIndexValue := c.index
+	depth := c.rewriteDepth + 1
+	if depth > engine.MaxRewriteDepth {
+		debugPrint("HandleContext: rewrite depth %d exceeds MaxRewriteDepth %d for %s, aborting", depth, engine.MaxRewriteDepth, c.Request.URL.Path)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
 	c.reset()
+	c.rewriteDepth = depth
 	engine.handleHTTPRequest(c)
 	c.index = oldIndexValue
 }
 
 func (engine *Engine) handleHTTPRequest(c *Context) {
 
-
 	httpMethod := c.Request.Method
 	path := c.Request.URL.Path
 	unescape := false
@@ -455,66 +1163,208 @@ func (engine *Engine) handleHTTPRequest(c *Context) {
 		unescape = engine.UnescapePathValues
 	}
 
-	
-	t := engine.trees
-	// 遍历路由树
+	// 去除每个路径段上的矩阵参数（如 ";jsessionid=..."），使其不影响路由匹配
+	if engine.RemoveMatrixParams {
+		path = removeMatrixParams(path)
+	}
+
+	// 无锁读取当前发布的路由树快照，即使有另一个 goroutine 正在通过
+	// addRoute/RemoveRoute 注册新路由也不会有数据竞争
+	t := engine.loadTrees()
+	// 如果请求的 Host 命中了某个 host 专属分组，只在该 host 的路由树里查找，
+	// 未命中的 Host 则落回默认（未绑定 host）的路由树。
+	if hostTrees, subdomain, ok := engine.hostTreesFor(c.Request.Host); ok {
+		t = hostTrees
+		if subdomain != "" {
+			c.Params = append(c.Params, Param{Key: "subdomain", Value: subdomain})
+		}
+	}
+	// needAllowed决定是否需要在本轮遍历里顺带收集其它method下该path的可用性，
+	// 避免命中当前method失败后，allowedMethods()再对所有树重新做一次getValue
+	// （尤其是重复对当前method的树再查一次，这是纯浪费）。
+	needAllowed := engine.HandleMethodNotAllowed || (engine.HandleOPTIONS && httpMethod == http.MethodOptions)
+	var allowed []string
+	var missedRoot *node
+	var missedTSR bool
+
+	// 遍历路由树：命中path的单次getValue在下面完成；未命中时，
+	// 仅当needAllowed时才会为其它method各自多付出一次getValue的代价。
 	for i, tl := 0, len(t); i < tl; i++ {
-		// 根据 http method 得到对应的路由子树
 		if t[i].method != httpMethod {
+			if needAllowed {
+				if handlers, _, _, _ := t[i].root.getValue(path, nil, unescape); handlers != nil {
+					allowed = append(allowed, t[i].method)
+				}
+			}
 			continue
 		}
 		// 树根
 		root := t[i].root
 		// 根据path查找 handlers
-		handlers, params, tsr := root.getValue(path, c.Params, unescape)
+		handlers, params, tsr, fullPath := root.getValue(path, c.Params, unescape)
 		// handlers 存在
 		if handlers != nil {
 			c.handlers = handlers
 			c.Params = params
+			c.fullPath = fullPath
+			if engine.Tracer != nil {
+				ctx, end := engine.Tracer.StartSpan(c.Request.Context(), fullPath)
+				c.Request = c.Request.WithContext(ctx)
+				defer end()
+			}
 			// (核心逻辑) 从第一个 handler 开始链式调用
 			c.Next()
 			// 写 Header
 			c.writermem.WriteHeaderNow()
 			return
 		}
+		missedRoot = root
+		missedTSR = tsr
+	}
 
-		 // 若 handlers 不存在且 method 不是 CONNECT 且 path 不是 /
-		if httpMethod != "CONNECT" && path != "/" {
-			// 如果配置是需要尾重定向，执行尾重定向
-			if tsr && engine.RedirectTrailingSlash {
-				redirectTrailingSlash(c)
-				return
-			}
-			// 如果不需要尾重定向但是配置了重定向固定 path, 重定向到固定 path
-			if engine.RedirectFixedPath && redirectFixedPath(c, root, engine.RedirectFixedPath) {
+	// 若 handlers 不存在且 method 不是 CONNECT 且 path 不是 /
+	if missedRoot != nil && httpMethod != "CONNECT" && path != "/" {
+		// 如果配置是需要尾重定向，执行尾重定向；具体分组可通过
+		// WithTrailingSlashRedirect 覆盖引擎级别的默认值
+		if missedTSR && bestBoolOverride(engine.groupTrailingSlash, path, engine.RedirectTrailingSlash) {
+			if engine.RedirectTrailingSlashRewrite {
+				c.Request.URL.Path = trailingSlashPath(path)
+				engine.HandleContext(c)
 				return
 			}
+			redirectTrailingSlash(c)
+			return
+		}
+		// 如果不需要尾重定向但是配置了重定向固定 path, 重定向到固定 path
+		if engine.RedirectFixedPath && redirectFixedPath(c, missedRoot, engine.RedirectFixedPath) {
+			return
 		}
-		break
 	}
 
-	// 如果是因为 HTTP method 有误，且配置了 HandleMethodNotAllowed 为 true，则处理如下处理
-	if engine.HandleMethodNotAllowed {
-		for _, tree := range engine.trees {
-			if tree.method == httpMethod {
-				continue
+	// 如果是因为 HTTP method 有误，且配置了 HandleMethodNotAllowed，或者是一个需要自动处理的
+	// OPTIONS 请求，上面的循环已经顺带收集好了该 path 下所有注册过的方法，填入 Allow 头部。
+	if needAllowed {
+		sortAllowedMethods(allowed)
+		if len(allowed) > 0 {
+			c.Writer.Header().Set("Allow", strings.Join(allowed, ", "))
+
+			// Even without the HandleOPTIONS opt-in, an OPTIONS request to a
+			// path that resolves via HandleMethodNotAllowed gets a clean
+			// 204 + Allow instead of falling through to the 405 body below
+			// (a user-registered OPTIONS handler always wins: the tree walk
+			// above only reaches here when none exists for this path).
+			if httpMethod == http.MethodOptions && (engine.HandleOPTIONS || engine.HandleMethodNotAllowed) {
+				c.AbortWithStatus(http.StatusNoContent)
+				return
 			}
-			// 路由中存在 method 不一样但是 path 和 params 匹配的路由，则返回 405 Method Not Allowed
-			if handlers, _, _ := tree.root.getValue(path, nil, unescape); handlers != nil {
-				c.handlers = engine.allNoMethod
+
+			if engine.HandleMethodNotAllowed {
+				c.handlers = bestFallback(engine.groupNoMethod, path, engine.allNoMethod)
 				serveError(c, http.StatusMethodNotAllowed, default405Body)
 				return
 			}
 		}
 	}
 
-	// 如果找不到匹配 route，返回404
-	c.handlers = engine.allNoRoute
+	// 如果找不到匹配 route，返回404；优先使用最匹配的分组级 NoRoute handler
+	c.handlers = bestFallback(engine.groupNoRoute, path, engine.allNoRoute)
 	serveError(c, http.StatusNotFound, default404Body)
 }
 
+// canonicalMethodOrder ranks the standard HTTP methods for a conventional,
+// deterministic Allow header; methods not listed here (custom verbs) sort
+// after all of these, alphabetically among themselves.
+var canonicalMethodOrder = map[string]int{
+	http.MethodGet:     0,
+	http.MethodHead:    1,
+	http.MethodPost:    2,
+	http.MethodPut:     3,
+	http.MethodPatch:   4,
+	http.MethodDelete:  5,
+	http.MethodOptions: 6,
+}
+
+// allowedMethods returns every HTTP method that has a route registered for
+// path within trees, sorted into the canonical order above, used to
+// populate the Allow header on 405 responses and auto-handled OPTIONS
+// requests.
+//
+// handleHTTPRequest no longer calls this directly: it collects the same
+// list in the same pass that looks up the request's own method, to avoid
+// a second getValue per tree (including a redundant one for the method
+// that already missed). allowedMethods stays as a standalone helper that
+// computes the Allow-header set for a path without going through a full
+// request; nothing in this package calls it.
+func allowedMethods(trees methodTrees, path string, unescape bool) []string {
+	var methods []string
+	for _, tree := range trees {
+		if handlers, _, _, _ := tree.root.getValue(path, nil, unescape); handlers != nil {
+			methods = append(methods, tree.method)
+		}
+	}
+	sortAllowedMethods(methods)
+	return methods
+}
+
+// sortAllowedMethods sorts an Allow-header method list into the canonical
+// order above, in place.
+func sortAllowedMethods(methods []string) {
+	sort.Slice(methods, func(i, j int) bool {
+		ri, oki := canonicalMethodOrder[methods[i]]
+		rj, okj := canonicalMethodOrder[methods[j]]
+		switch {
+		case oki && okj:
+			return ri < rj
+		case oki != okj:
+			return oki
+		default:
+			return methods[i] < methods[j]
+		}
+	})
+}
+
+// hostTreesFor returns the method trees registered for reqHost (port
+// stripped), matching an exact host first and then any "*.example.com"
+// wildcard pattern, in which case subdomain is the matched leading label.
+// ok is false when reqHost matches nothing, meaning the caller should use
+// the default (non-host-scoped) trees instead.
+func (engine *Engine) hostTreesFor(reqHost string) (trees methodTrees, subdomain string, ok bool) {
+	hostTrees := engine.loadHostTrees()
+	if len(hostTrees) == 0 {
+		return nil, "", false
+	}
+
+	host := reqHost
+	if i := strings.IndexByte(host, ':'); i >= 0 {
+		host = host[:i]
+	}
+
+	if trees, ok = hostTrees[host]; ok {
+		return trees, "", true
+	}
+
+	for pattern, t := range hostTrees {
+		if !strings.HasPrefix(pattern, "*.") {
+			continue
+		}
+		suffix := pattern[1:] // ".example.com"
+		if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+			return t, strings.TrimSuffix(host, suffix), true
+		}
+	}
+
+	return nil, "", false
+}
+
 var mimePlain = []string{MIMEPlain}
 
+// apiErrorMessages maps the default 404/405 status codes to the "message"
+// field used by Engine.APIErrorMode.
+var apiErrorMessages = map[int]string{
+	http.StatusNotFound:         "not found",
+	http.StatusMethodNotAllowed: "method not allowed",
+}
+
 func serveError(c *Context, code int, defaultMessage []byte) {
 	c.writermem.status = code
 	c.Next()
@@ -522,6 +1372,22 @@ func serveError(c *Context, code int, defaultMessage []byte) {
 		return
 	}
 	if c.writermem.Status() == code {
+		if contentType, body := c.engine.customErrorBody(code); body != nil {
+			c.writermem.Header()["Content-Type"] = []string{contentType}
+			_, err := c.Writer.Write(body)
+			if err != nil {
+				debugPrint("cannot write message to writer during serve error: %v", err)
+			}
+			return
+		}
+		if c.engine.APIErrorMode {
+			message, ok := apiErrorMessages[code]
+			if !ok {
+				message = http.StatusText(code)
+			}
+			c.JSON(code, H{"code": code, "message": message})
+			return
+		}
 		c.writermem.Header()["Content-Type"] = mimePlain
 		_, err := c.Writer.Write(defaultMessage)
 		if err != nil {
@@ -533,27 +1399,69 @@ func serveError(c *Context, code int, defaultMessage []byte) {
 	return
 }
 
+// customErrorBody returns the Content-Type/body configured via SetDefault404
+// or SetDefault405 for code, or a nil body if none was configured.
+func (engine *Engine) customErrorBody(code int) (contentType string, body []byte) {
+	switch code {
+	case http.StatusNotFound:
+		return engine.custom404ContentType, engine.custom404Body
+	case http.StatusMethodNotAllowed:
+		return engine.custom405ContentType, engine.custom405Body
+	default:
+		return "", nil
+	}
+}
+
+// removeMatrixParams strips a ";key=value" matrix parameter suffix from
+// each "/"-separated segment of path, e.g. "/foo;jsessionid=1234/bar"
+// becomes "/foo/bar". Segments without a ";" are left untouched.
+func removeMatrixParams(path string) string {
+	if !strings.ContainsRune(path, ';') {
+		return path
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if idx := strings.IndexByte(segment, ';'); idx >= 0 {
+			segments[i] = segment[:idx]
+		}
+	}
+	return strings.Join(segments, "/")
+}
 
-//尾重定向
+// trailingSlashPath returns path with its trailing slash toggled: added if
+// absent, removed if present (the counterpart path a tsr match was found
+// under).
+func trailingSlashPath(path string) string {
+	if length := len(path); length > 1 && path[length-1] == '/' {
+		return path[:length-1]
+	}
+	return path + "/"
+}
+
+// 尾重定向
 func redirectTrailingSlash(c *Context) {
 	req := c.Request
 	path := req.URL.Path
-	code := http.StatusMovedPermanently // Permanent redirect, request with GET method
-	if req.Method != "GET" {
+
+	var code int
+	if c.engine.RedirectMethodPreserving {
+		// 308 preserves the method and body for every request method, so
+		// there's no need to special-case GET here the way 301/307 do.
+		code = http.StatusPermanentRedirect
+	} else if req.Method == "GET" {
+		code = http.StatusMovedPermanently // Permanent redirect, request with GET method
+	} else {
 		code = http.StatusTemporaryRedirect
 	}
 
-	req.URL.Path = path + "/"
-	if length := len(path); length > 1 && path[length-1] == '/' {
-		req.URL.Path = path[:length-1]
-	}
+	req.URL.Path = trailingSlashPath(path)
 	debugPrint("redirecting request %d: %s --> %s", code, path, req.URL.String())
 	http.Redirect(c.Writer, req, req.URL.String(), code)
 	c.writermem.WriteHeaderNow()
 }
 
-
-//固定路由重定向
+// 固定路由重定向
 func redirectFixedPath(c *Context, root *node, trailingSlash bool) bool {
 	req := c.Request
 	path := req.URL.Path
@@ -11,8 +11,12 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -165,6 +169,94 @@ func TestLoadHTMLGlobFromFuncMap(t *testing.T) {
 	assert.Equal(t, "Date: 2017/07/01\n", string(resp))
 }
 
+func TestLoadHTMLGlobWatchReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	tmplPath := filepath.Join(dir, "hello.tmpl")
+	assert.NoError(t, ioutil.WriteFile(tmplPath, []byte("<h1>v1 {[{.name}]}</h1>"), 0o644))
+
+	var router *Engine
+	var stop func()
+	captureOutput(t, func() {
+		router = New()
+		router.Delims("{[{", "}]}")
+		stop = router.LoadHTMLGlobWatch(filepath.Join(dir, "*"), 10*time.Millisecond)
+	})
+	defer stop()
+
+	s, err := router.RenderHTMLString("hello.tmpl", map[string]string{"name": "world"})
+	assert.NoError(t, err)
+	assert.Equal(t, "<h1>v1 world</h1>", s)
+
+	// back-date the original write so the update below is unambiguously
+	// newer even on filesystems with coarse modtime resolution.
+	past := time.Now().Add(-time.Minute)
+	assert.NoError(t, os.Chtimes(tmplPath, past, past))
+	assert.NoError(t, ioutil.WriteFile(tmplPath, []byte("<h1>v2 {[{.name}]}</h1>"), 0o644))
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		s, err := router.RenderHTMLString("hello.tmpl", map[string]string{"name": "world"})
+		assert.NoError(t, err)
+		if s == "<h1>v2 world</h1>" || time.Now().After(deadline) {
+			assert.Equal(t, "<h1>v2 world</h1>", s)
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRenderHTMLString(t *testing.T) {
+	var s string
+	var err error
+	captureOutput(t, func() {
+		router := New()
+		router.Delims("{[{", "}]}")
+		router.SetFuncMap(template.FuncMap{
+			"formatAsDate": formatAsDate,
+		})
+		router.LoadHTMLGlob("./testdata/template/*")
+
+		s, err = router.RenderHTMLString("hello.tmpl", map[string]string{"name": "world"})
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "<h1>Hello world</h1>", s)
+}
+
+func TestRenderHTMLStringUsesFuncMap(t *testing.T) {
+	var s string
+	var err error
+	captureOutput(t, func() {
+		router := New()
+		router.Delims("{[{", "}]}")
+		router.SetFuncMap(template.FuncMap{
+			"formatAsDate": formatAsDate,
+		})
+		router.LoadHTMLGlob("./testdata/template/*")
+
+		s, err = router.RenderHTMLString("raw.tmpl", map[string]interface{}{
+			"now": time.Date(2017, 07, 01, 0, 0, 0, 0, time.UTC),
+		})
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Date: 2017/07/01\n", s)
+}
+
+func TestRenderHTMLStringNoRendererRegistered(t *testing.T) {
+	router := New()
+	_, err := router.RenderHTMLString("hello.tmpl", nil)
+	assert.Error(t, err)
+}
+
+func TestRenderHTMLStringTemplateError(t *testing.T) {
+	var err error
+	captureOutput(t, func() {
+		router := New()
+		router.LoadHTMLGlob("./testdata/template/*")
+		_, err = router.RenderHTMLString("does-not-exist.tmpl", nil)
+	})
+	assert.Error(t, err)
+}
+
 func init() {
 	SetMode(TestMode)
 }
@@ -287,18 +379,18 @@ func TestAddRoute(t *testing.T) {
 	router := New()
 	router.addRoute("GET", "/", HandlersChain{func(_ *Context) {}})
 
-	assert.Len(t, router.trees, 1)
-	assert.NotNil(t, router.trees.get("GET"))
-	assert.Nil(t, router.trees.get("POST"))
+	assert.Len(t, router.loadTrees(), 1)
+	assert.NotNil(t, router.loadTrees().get("GET"))
+	assert.Nil(t, router.loadTrees().get("POST"))
 
 	router.addRoute("POST", "/", HandlersChain{func(_ *Context) {}})
 
-	assert.Len(t, router.trees, 2)
-	assert.NotNil(t, router.trees.get("GET"))
-	assert.NotNil(t, router.trees.get("POST"))
+	assert.Len(t, router.loadTrees(), 2)
+	assert.NotNil(t, router.loadTrees().get("GET"))
+	assert.NotNil(t, router.loadTrees().get("POST"))
 
 	router.addRoute("POST", "/post", HandlersChain{func(_ *Context) {}})
-	assert.Len(t, router.trees, 2)
+	assert.Len(t, router.loadTrees(), 2)
 }
 
 func TestAddRouteFails(t *testing.T) {
@@ -478,6 +570,48 @@ func TestListOfRoutes(t *testing.T) {
 	})
 }
 
+func TestListOfRoutesIsSorted(t *testing.T) {
+	router := New()
+	router.POST("/users/:id", handlerTest2)
+	router.GET("/users/:id", handlerTest1)
+	router.GET("/", handlerTest1)
+	router.GET("/favicon.ico", handlerTest1)
+
+	list := router.Routes()
+	assert.Len(t, list, 4)
+	for i := 1; i < len(list); i++ {
+		prev, cur := list[i-1], list[i]
+		if prev.Method != cur.Method {
+			assert.True(t, prev.Method < cur.Method)
+			continue
+		}
+		assert.True(t, prev.Path < cur.Path)
+	}
+}
+
+func TestEngineAddRouteSafeReportsConflictsInsteadOfPanicking(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", handlerTest1)
+
+	assert.Panics(t, func() {
+		router.GET("/users/new", handlerTest2)
+	})
+
+	err := router.AddRouteSafe("GET", "/users/new", HandlersChain{handlerTest2})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "conflicts with existing")
+
+	// the rejected route must not have been registered.
+	w := performRequest(router, "GET", "/users/new")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// a non-conflicting route still registers normally.
+	err = router.AddRouteSafe("GET", "/posts/:id", HandlersChain{handlerTest1})
+	assert.NoError(t, err)
+	w = performRequest(router, "GET", "/posts/1")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
 func TestEngineHandleContext(t *testing.T) {
 	r := New()
 	r.GET("/", func(c *Context) {
@@ -501,6 +635,7 @@ func TestEngineHandleContextManyReEntries(t *testing.T) {
 	var handlerCounter, middlewareCounter int64
 
 	r := New()
+	r.MaxRewriteDepth = expectValue // this test deliberately re-enters far more than the default cap
 	r.Use(func(c *Context) {
 		atomic.AddInt64(&middlewareCounter, 1)
 	})
@@ -532,6 +667,81 @@ func TestEngineHandleContextManyReEntries(t *testing.T) {
 	assert.Equal(t, int64(expectValue), middlewareCounter)
 }
 
+func TestEngineHandleContextRewriteDepthLimit(t *testing.T) {
+	r := New()
+	r.GET("/loop", func(c *Context) {
+		c.Request.URL.Path = "/loop"
+		r.HandleContext(c)
+	})
+
+	assert.NotPanics(t, func() {
+		w := performRequest(r, "GET", "/loop")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestEngineHandleContextRewriteDepthResetsBetweenRequests(t *testing.T) {
+	r := New()
+	r.MaxRewriteDepth = 2
+	r.GET("/a", func(c *Context) {
+		c.Request.URL.Path = "/b"
+		r.HandleContext(c)
+	})
+	r.GET("/b", func(c *Context) {})
+
+	w := performRequest(r, "GET", "/a")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A second, independent top-level request should not inherit the depth
+	// spent by the first one, since reset() zeroes it for every new request.
+	w = performRequest(r, "GET", "/a")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestEngineMaxRequestBodySize(t *testing.T) {
+	type payload struct {
+		Message string `json:"message" binding:"required"`
+	}
+
+	r := New()
+	r.MaxRequestBodySize = 16
+	r.POST("/upload", func(c *Context) {
+		var p payload
+		if err := c.BindJSON(&p); err != nil {
+			return
+		}
+		c.String(http.StatusOK, p.Message)
+	})
+
+	req := httptest.NewRequest("POST", "/upload", strings.NewReader(`{"message":"this body is way too long"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestEngineMaxRequestBodySizeZeroMeansUnlimited(t *testing.T) {
+	type payload struct {
+		Message string `json:"message" binding:"required"`
+	}
+
+	r := New()
+	r.POST("/upload", func(c *Context) {
+		var p payload
+		if err := c.BindJSON(&p); err != nil {
+			return
+		}
+		c.String(http.StatusOK, p.Message)
+	})
+
+	req := httptest.NewRequest("POST", "/upload", strings.NewReader(`{"message":"this body is way too long"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "this body is way too long", w.Body.String())
+}
+
 func assertRoutePresent(t *testing.T, gotRoutes RoutesInfo, wantRoute RouteInfo) {
 	for _, gotRoute := range gotRoutes {
 		if gotRoute.Path == wantRoute.Path && gotRoute.Method == wantRoute.Method {
@@ -544,3 +754,90 @@ func assertRoutePresent(t *testing.T, gotRoutes RoutesInfo, wantRoute RouteInfo)
 
 func handlerTest1(c *Context) {}
 func handlerTest2(c *Context) {}
+
+// TestConcurrentRouteRegistrationAndServing registers new routes from one
+// goroutine while other goroutines keep serving requests through the
+// engine, the scenario a control-plane service hits when it adds routes
+// after Run has already started. Run with -race: a data race here would
+// mean handleHTTPRequest observed a tree mid-mutation.
+func TestConcurrentRouteRegistrationAndServing(t *testing.T) {
+	router := New()
+	router.GET("/static", func(c *Context) { c.String(http.StatusOK, "static") })
+
+	const numRoutes = 50
+	const numRequests = 200
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < numRoutes; i++ {
+			path := fmt.Sprintf("/dynamic/%d", i)
+			router.GET(path, func(c *Context) { c.String(http.StatusOK, "dynamic") })
+		}
+	}()
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < numRequests; i++ {
+				res := performRequest(router, "GET", "/static")
+				assert.Equal(t, http.StatusOK, res.Code)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for i := 0; i < numRoutes; i++ {
+		path := fmt.Sprintf("/dynamic/%d", i)
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, http.StatusOK, res.Code)
+	}
+}
+
+// TestConcurrentHostRouteRegistrationAndServing is
+// TestConcurrentRouteRegistrationAndServing's counterpart for host-scoped
+// routes added via Engine.Host: registering a route under an existing host
+// while other goroutines keep serving requests on that same host must not
+// race on the engine's host trees. Run with -race.
+func TestConcurrentHostRouteRegistrationAndServing(t *testing.T) {
+	router := New()
+	host := router.Host("api.example.com")
+	host.GET("/static", func(c *Context) { c.String(http.StatusOK, "static") })
+
+	const numRoutes = 50
+	const numRequests = 200
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < numRoutes; i++ {
+			path := fmt.Sprintf("/dynamic/%d", i)
+			host.GET(path, func(c *Context) { c.String(http.StatusOK, "dynamic") })
+		}
+	}()
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < numRequests; i++ {
+				res := performHostRequest(router, "GET", "/static", "api.example.com")
+				assert.Equal(t, http.StatusOK, res.Code)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for i := 0; i < numRoutes; i++ {
+		path := fmt.Sprintf("/dynamic/%d", i)
+		res := performHostRequest(router, "GET", path, "api.example.com")
+		assert.Equal(t, http.StatusOK, res.Code)
+	}
+}
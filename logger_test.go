@@ -201,8 +201,10 @@ func TestLoggerWithConfigFormatting(t *testing.T) {
 			)
 		},
 	}))
+	assert.NoError(t, router.SetTrustedProxies([]string{"192.0.2.1/32"}))
 	router.GET("/example", func(c *Context) {
 		// set dummy ClientIP
+		c.Request.RemoteAddr = "192.0.2.1:1234"
 		c.Request.Header.Set("X-Forwarded-For", "20.20.20.20")
 	})
 	performRequest(router, "GET", "/example?a=100")
@@ -7,6 +7,7 @@ package render
 import (
 	"html/template"
 	"net/http"
+	"sync/atomic"
 )
 
 // Delims represents a set of Left and Right delimiters for HTML template rendering.
@@ -37,6 +38,34 @@ type HTMLDebug struct {
 	FuncMap template.FuncMap
 }
 
+// HTMLTemplateWatch holds an HTML template behind an atomic pointer, so a
+// background reloader can swap it for a freshly parsed one without any
+// concurrent Instance call ever observing a half-parsed template: a reader
+// gets either the whole template set from before the swap or the whole one
+// from after it, never something in between.
+type HTMLTemplateWatch struct {
+	tmpl atomic.Pointer[template.Template]
+}
+
+// Store installs templ as the template set served by future Instance calls.
+func (r *HTMLTemplateWatch) Store(templ *template.Template) {
+	r.tmpl.Store(templ)
+}
+
+// Load returns the template set currently installed via Store.
+func (r *HTMLTemplateWatch) Load() *template.Template {
+	return r.tmpl.Load()
+}
+
+// Instance (HTMLTemplateWatch) returns an HTML instance which it realizes Render interface.
+func (r *HTMLTemplateWatch) Instance(name string, data interface{}) Render {
+	return HTML{
+		Template: r.Load(),
+		Name:     name,
+		Data:     data,
+	}
+}
+
 // HTML contains template reference and its name with given interface object.
 type HTML struct {
 	Template *template.Template
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"html/template"
 	"net/http"
+	"unicode/utf16"
 
 	"github.com/gin-gonic/gin/internal/json"
 )
@@ -47,6 +48,22 @@ var jsonContentType 		= []string{"application/json; charset=utf-8"}
 var jsonpContentType	 	= []string{"application/javascript; charset=utf-8"}
 var jsonAsciiContentType 	= []string{"application/json"}
 
+// jsonMarshal is consulted by JSON, SecureJSON, JsonpJSON and AsciiJSON to
+// encode their data. Defaults to the standard library (or jsoniter, if gin
+// was built with the "jsoniter" tag); override with SetJSONEncoder.
+var jsonMarshal = json.Marshal
+
+// SetJSONEncoder installs marshal as the encoder used by JSON, SecureJSON,
+// JsonpJSON and AsciiJSON process-wide, e.g. to swap in a drop-in faster
+// encoder without forking. IndentedJSON is not affected: it always encodes
+// with json.MarshalIndent. Passing nil restores the default.
+func SetJSONEncoder(marshal func(interface{}) ([]byte, error)) {
+	if marshal == nil {
+		marshal = json.Marshal
+	}
+	jsonMarshal = marshal
+}
+
 
 
 
@@ -66,7 +83,7 @@ func (r JSON) WriteContentType(w http.ResponseWriter) {
 // WriteJSON marshals the given interface object and writes it with custom ContentType.
 func WriteJSON(w http.ResponseWriter, obj interface{}) error {
 	writeContentType(w, jsonContentType) // 写入 "application/json; charset=utf-8"
-	jsonBytes, err := json.Marshal(obj)
+	jsonBytes, err := jsonMarshal(obj)
 	if err != nil {
 		return err
 	}
@@ -96,7 +113,7 @@ func (r IndentedJSON) WriteContentType(w http.ResponseWriter) {
 // Render (SecureJSON) marshals the given interface object and writes it with custom ContentType.
 func (r SecureJSON) Render(w http.ResponseWriter) error {
 	r.WriteContentType(w)
-	jsonBytes, err := json.Marshal(r.Data)
+	jsonBytes, err := jsonMarshal(r.Data)
 	if err != nil {
 		return err
 	}
@@ -122,7 +139,7 @@ func (r JsonpJSON) Render(w http.ResponseWriter) (err error) {
 
 	//
 	r.WriteContentType(w)
-	ret, err := json.Marshal(r.Data)
+	ret, err := jsonMarshal(r.Data)
 	if err != nil {
 		return err
 	}
@@ -161,18 +178,26 @@ func (r JsonpJSON) WriteContentType(w http.ResponseWriter) {
 // Render (AsciiJSON) marshals the given interface object and writes it with custom ContentType.
 func (r AsciiJSON) Render(w http.ResponseWriter) (err error) {
 	r.WriteContentType(w)
-	ret, err := json.Marshal(r.Data)
+	ret, err := jsonMarshal(r.Data)
 	if err != nil {
 		return err
 	}
 
 	var buffer bytes.Buffer
 	for _, r := range string(ret) {
-		cvt := string(r)
-		if r >= 128 {
-			cvt = fmt.Sprintf("\\u%04x", int64(r))
+		if r < 128 {
+			buffer.WriteRune(r)
+			continue
+		}
+		if r > 0xFFFF {
+			// JSON \u escapes are exactly 4 hex digits, so code points
+			// outside the Basic Multilingual Plane (e.g. most emoji) must
+			// be split into a UTF-16 surrogate pair.
+			r1, r2 := utf16.EncodeRune(r)
+			fmt.Fprintf(&buffer, "\\u%04x\\u%04x", r1, r2)
+			continue
 		}
-		buffer.WriteString(cvt)
+		fmt.Fprintf(&buffer, "\\u%04x", r)
 	}
 
 	_, err = w.Write(buffer.Bytes())
@@ -5,7 +5,9 @@
 package render
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/golang/protobuf/proto"
 )
@@ -19,13 +21,18 @@ var protobufContentType = []string{"application/x-protobuf"}
 
 // Render (ProtoBuf) marshals the given interface object and writes data with custom ContentType.
 func (r ProtoBuf) Render(w http.ResponseWriter) error {
-	r.WriteContentType(w)
+	msg, ok := r.Data.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf: %T does not implement proto.Message", r.Data)
+	}
 
-	bytes, err := proto.Marshal(r.Data.(proto.Message))
+	bytes, err := proto.Marshal(msg)
 	if err != nil {
 		return err
 	}
 
+	r.WriteContentType(w)
+	w.Header().Set("Content-Length", strconv.Itoa(len(bytes)))
 	_, err = w.Write(bytes)
 	return err
 }
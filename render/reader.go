@@ -18,13 +18,30 @@ type Reader struct {
 	Headers       map[string]string
 }
 
-// Render (Reader) writes data with custom ContentType and headers.
+// Render (Reader) writes data with custom ContentType and headers, copying
+// r.Reader to w with io.Copy so the whole body never has to sit in memory
+// at once. If r.Reader is also an io.Closer (an *os.File, the body of an
+// outgoing http.Response, ...) it's closed once the copy is done, and if w
+// is an http.Flusher the written bytes are flushed immediately afterwards.
 func (r Reader) Render(w http.ResponseWriter) (err error) {
 	r.WriteContentType(w)
+	if r.Headers == nil {
+		r.Headers = map[string]string{}
+	}
 	r.Headers["Content-Length"] = strconv.FormatInt(r.ContentLength, 10)
 	r.writeHeaders(w, r.Headers)
-	_, err = io.Copy(w, r.Reader)
-	return
+
+	if closer, ok := r.Reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	if _, err = io.Copy(w, r.Reader); err != nil {
+		return err
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
 }
 
 // WriteContentType (Reader) writes custom ContentType.
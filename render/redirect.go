@@ -9,6 +9,17 @@ import (
 	"net/http"
 )
 
+// ErrInvalidRedirectCode is returned by Redirect.Render when Code is
+// neither a 3xx status nor 201, both of which are valid Location-header
+// responses.
+type ErrInvalidRedirectCode struct {
+	Code int
+}
+
+func (e *ErrInvalidRedirectCode) Error() string {
+	return fmt.Sprintf("cannot redirect with status code %d", e.Code)
+}
+
 
 
 // HTTP 协议的重定向响应的状态码为 3xx 。
@@ -25,14 +36,34 @@ type Redirect struct {
 	Code     int
 	Request  *http.Request
 	Location string
+
+	// Relative, when true, skips http.Redirect's resolution of Location
+	// against the request URL: the Location header is written verbatim and
+	// the status is set with w.WriteHeader(Code) directly. Use this to
+	// force an absolute-path redirect (a leading "/") or a purely relative
+	// one regardless of the current request path, or to redirect to an
+	// external absolute URL without http.Redirect second-guessing it.
+	Relative bool
 }
 
 // Render (Redirect) redirects the http request to new location and writes redirect response.
+//
+// Code must be a 3xx status or 201; any other value returns
+// *ErrInvalidRedirectCode instead of panicking, leaving the decision of
+// what to do about it (log, return 500, retry with a different code) to
+// the caller. Context.Render still turns that error into a panic on behalf
+// of a gin handler, since there's no sane response left to send once
+// headers may already be written, but anyone calling Render directly gets
+// a normal error.
 func (r Redirect) Render(w http.ResponseWriter) error {
-	// todo(thinkerou): go1.6 not support StatusPermanentRedirect(308)
-	// when we upgrade go version we can use http.StatusPermanentRedirect
-	if (r.Code < 300 || r.Code > 308) && r.Code != 201 {
-		panic(fmt.Sprintf("Cannot redirect with status code %d", r.Code))
+	if r.Code != 201 && (r.Code < 300 || r.Code >= 400) {
+		return &ErrInvalidRedirectCode{Code: r.Code}
+	}
+
+	if r.Relative {
+		w.Header().Set("Location", r.Location)
+		w.WriteHeader(r.Code)
+		return nil
 	}
 
 	http.Redirect(w, r.Request, r.Location, r.Code)
@@ -6,6 +6,7 @@ package render
 
 import (
 	"bytes"
+	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"html/template"
@@ -74,6 +75,37 @@ func TestRenderJSONPanics(t *testing.T) {
 	assert.Panics(t, func() { assert.NoError(t, (JSON{data}).Render(w)) })
 }
 
+func TestSetJSONEncoderUsesCustomMarshal(t *testing.T) {
+	defer SetJSONEncoder(nil)
+
+	var called bool
+	SetJSONEncoder(func(v interface{}) ([]byte, error) {
+		called = true
+		return []byte(`"stubbed"`), nil
+	})
+
+	w := httptest.NewRecorder()
+	err := (JSON{map[string]string{"foo": "bar"}}).Render(w)
+
+	assert.NoError(t, err)
+	assert.True(t, called)
+	assert.Equal(t, `"stubbed"`, w.Body.String())
+}
+
+func TestSetJSONEncoderNilRestoresDefault(t *testing.T) {
+	SetJSONEncoder(func(v interface{}) ([]byte, error) {
+		return []byte(`"stubbed"`), nil
+	})
+	SetJSONEncoder(nil)
+	defer SetJSONEncoder(nil)
+
+	w := httptest.NewRecorder()
+	err := (JSON{map[string]string{"foo": "bar"}}).Render(w)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"foo":"bar"}`, w.Body.String())
+}
+
 func TestRenderIndentedJSON(t *testing.T) {
 	w := httptest.NewRecorder()
 	data := map[string]interface{}{
@@ -207,6 +239,22 @@ func TestRenderAsciiJSON(t *testing.T) {
 	assert.Equal(t, "3.1415926", w2.Body.String())
 }
 
+func TestRenderAsciiJSONEmoji(t *testing.T) {
+	w := httptest.NewRecorder()
+	data := map[string]interface{}{"emoji": "😀"}
+
+	err := (AsciiJSON{data}).Render(w)
+
+	assert.NoError(t, err)
+	// U+1F600 is outside the Basic Multilingual Plane, so it must be
+	// written as a UTF-16 surrogate pair to stay valid JSON.
+	assert.Equal(t, "{\"emoji\":\"\\ud83d\\ude00\"}", w.Body.String())
+
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &decoded))
+	assert.Equal(t, "😀", decoded["emoji"])
+}
+
 func TestRenderAsciiJSONFail(t *testing.T) {
 	w := httptest.NewRecorder()
 	data := make(chan int)
@@ -298,6 +346,12 @@ func TestRenderProtoBufFail(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestRenderProtoBufNotAMessage(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := (ProtoBuf{"not a proto.Message"}).Render(w)
+	assert.Error(t, err)
+}
+
 func TestRenderXML(t *testing.T) {
 	w := httptest.NewRecorder()
 	data := xmlmap{
@@ -335,12 +389,49 @@ func TestRenderRedirect(t *testing.T) {
 	}
 
 	w = httptest.NewRecorder()
-	assert.Panics(t, func() { assert.NoError(t, data2.Render(w)) })
+	err = data2.Render(w)
+	assert.Error(t, err)
+	assert.IsType(t, &ErrInvalidRedirectCode{}, err)
 
 	// only improve coverage
 	data2.WriteContentType(w)
 }
 
+func TestRenderRedirectAcceptsAny3xxAnd201(t *testing.T) {
+	req, err := http.NewRequest("GET", "/test-redirect", nil)
+	assert.NoError(t, err)
+
+	for _, code := range []int{300, 301, 302, 303, 304, 305, 307, 308, 309, 399, http.StatusCreated} {
+		w := httptest.NewRecorder()
+		err := (Redirect{Code: code, Request: req, Location: "/new/location"}).Render(w)
+		assert.NoError(t, err, "code %d", code)
+	}
+
+	for _, code := range []int{100, 200, 299, 400, 500} {
+		w := httptest.NewRecorder()
+		err := (Redirect{Code: code, Request: req, Location: "/new/location"}).Render(w)
+		assert.Error(t, err, "code %d", code)
+	}
+}
+
+func TestRenderRedirectRelative(t *testing.T) {
+	req, err := http.NewRequest("GET", "/current/path", nil)
+	assert.NoError(t, err)
+
+	data := Redirect{
+		Code:     http.StatusFound,
+		Request:  req,
+		Location: "https://example.com/elsewhere",
+		Relative: true,
+	}
+
+	w := httptest.NewRecorder()
+	err = data.Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://example.com/elsewhere", w.Header().Get("Location"))
+}
+
 func TestRenderData(t *testing.T) {
 	w := httptest.NewRecorder()
 	data := []byte("#!PNG some raw data")
@@ -484,3 +575,44 @@ func TestRenderReader(t *testing.T) {
 	assert.Equal(t, strconv.Itoa(len(body)), w.Header().Get("Content-Length"))
 	assert.Equal(t, headers["Content-Disposition"], w.Header().Get("Content-Disposition"))
 }
+
+func TestRenderReaderNilHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	body := "data"
+	err := (Reader{
+		ContentLength: int64(len(body)),
+		ContentType:   "application/octet-stream",
+		Reader:        strings.NewReader(body),
+	}).Render(w)
+
+	assert.NoError(t, err)
+	assert.Equal(t, body, w.Body.String())
+	assert.Equal(t, strconv.Itoa(len(body)), w.Header().Get("Content-Length"))
+}
+
+type closeTrackingReader struct {
+	*strings.Reader
+	closed bool
+}
+
+func (c *closeTrackingReader) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestRenderReaderClosesReaderWhenDone(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	body := "data"
+	reader := &closeTrackingReader{Reader: strings.NewReader(body)}
+
+	err := (Reader{
+		ContentLength: int64(len(body)),
+		ContentType:   "application/octet-stream",
+		Reader:        reader,
+	}).Render(w)
+
+	assert.NoError(t, err)
+	assert.True(t, reader.closed)
+}
@@ -50,6 +50,10 @@ type responseWriter struct {
 	http.ResponseWriter
 	size   int
 	status int
+
+	// statusRewriter, when set, transforms the status right before it's
+	// committed to the wire. See Engine.StatusRewriter.
+	statusRewriter func(code int) int
 }
 
 var _ ResponseWriter = &responseWriter{}
@@ -58,6 +62,7 @@ func (w *responseWriter) reset(writer http.ResponseWriter) {
 	w.ResponseWriter = writer
 	w.size = noWritten
 	w.status = defaultStatus
+	w.statusRewriter = nil
 }
 
 func (w *responseWriter) WriteHeader(code int) {
@@ -72,7 +77,14 @@ func (w *responseWriter) WriteHeader(code int) {
 func (w *responseWriter) WriteHeaderNow() {
 	if !w.Written() {
 		w.size = 0
-		w.ResponseWriter.WriteHeader(w.status)
+		status := w.status
+		if w.statusRewriter != nil {
+			if rewritten := w.statusRewriter(status); rewritten > 0 {
+				status = rewritten
+			}
+			w.status = status
+		}
+		w.ResponseWriter.WriteHeader(status)
 	}
 }
 
@@ -102,12 +114,21 @@ func (w *responseWriter) Written() bool {
 	return w.size != noWritten
 }
 
-// Hijack implements the http.Hijacker interface.
+// Hijack implements the http.Hijacker interface, delegating to the wrapped
+// http.ResponseWriter when it supports hijacking (as net/http's does for
+// plain TCP/TLS connections). On success the response is marked as written
+// so a later WriteHeaderNow doesn't try to write a status over the hijacked
+// connection. If the wrapped writer doesn't support hijacking, it returns
+// http.ErrNotSupported without touching the writer's state.
 func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
 	if w.size < 0 {
 		w.size = 0
 	}
-	return w.ResponseWriter.(http.Hijacker).Hijack()
+	return hijacker.Hijack()
 }
 
 // CloseNotify implements the http.CloseNotify interface.
@@ -5,6 +5,8 @@
 package gin
 
 import (
+	"bufio"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -102,11 +104,9 @@ func TestResponseWriterHijack(t *testing.T) {
 	writer.reset(testWritter)
 	w := ResponseWriter(writer)
 
-	assert.Panics(t, func() {
-		_, _, err := w.Hijack()
-		assert.NoError(t, err)
-	})
-	assert.True(t, w.Written())
+	_, _, err := w.Hijack()
+	assert.Equal(t, http.ErrNotSupported, err)
+	assert.False(t, w.Written())
 
 	assert.Panics(t, func() {
 		w.CloseNotify()
@@ -115,6 +115,30 @@ func TestResponseWriterHijack(t *testing.T) {
 	w.Flush()
 }
 
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+func (h *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return h.conn, bufio.NewReadWriter(bufio.NewReader(h.conn), bufio.NewWriter(h.conn)), nil
+}
+
+func TestResponseWriterHijackSupported(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	recorder := &hijackableRecorder{ResponseRecorder: httptest.NewRecorder(), conn: server}
+
+	writer := &responseWriter{}
+	writer.reset(recorder)
+	w := ResponseWriter(writer)
+
+	conn, _, err := w.Hijack()
+	assert.NoError(t, err)
+	assert.Equal(t, server, conn)
+	assert.True(t, w.Written())
+}
+
 func TestResponseWriterFlush(t *testing.T) {
 	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		writer := &responseWriter{}
@@ -5,9 +5,11 @@
 package gin
 
 import (
+	"fmt"
 	"net/http"
 	"path"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -23,6 +25,7 @@ type IRoutes interface {
 
 	Handle(string, string, ...HandlerFunc) IRoutes
 	Any(string, ...HandlerFunc) IRoutes
+	Match([]string, string, ...HandlerFunc) IRoutes
 	GET(string, ...HandlerFunc) IRoutes
 	POST(string, ...HandlerFunc) IRoutes
 	DELETE(string, ...HandlerFunc) IRoutes
@@ -34,6 +37,10 @@ type IRoutes interface {
 	StaticFile(string, string) IRoutes
 	Static(string, string) IRoutes
 	StaticFS(string, http.FileSystem) IRoutes
+
+	Name(string) IRoutes
+
+	WithHEADAndOPTIONS() IRoutes
 }
 
 // RouterGroup is used internally to configure router, a RouterGroup is associated with
@@ -41,10 +48,21 @@ type IRoutes interface {
 
 // RouterGroup 描述的是路由的一个父节点，里面包含了父节点的一些属性
 type RouterGroup struct {
-    Handlers HandlersChain 	// 父节点路由的监听器，实际上最后也是一个带有上下文指针的回调
-    basePath string  		// 路由路径，相对于子路由的上级路径
-    engine   *Engine 		// 父节点路由的Engine实体
-    root     bool   		// 是否为根节点路由
+	Handlers HandlersChain // 父节点路由的监听器，实际上最后也是一个带有上下文指针的回调
+	basePath string        // 路由路径，相对于子路由的上级路径
+	engine   *Engine       // 父节点路由的Engine实体
+	root     bool          // 是否为根节点路由
+	host     string        // 限定该分组路由只匹配的 Host，空串表示不限定（默认分组）
+
+	// lastRoute records the most recently registered route on this group,
+	// consulted by WithHEADAndOPTIONS to derive its sibling registrations.
+	lastRoute *lastRegisteredRoute
+}
+
+type lastRegisteredRoute struct {
+	method       string
+	absolutePath string
+	handlers     HandlersChain
 }
 
 var _ IRouter = &RouterGroup{}
@@ -62,6 +80,7 @@ func (group *RouterGroup) Group(relativePath string, handlers ...HandlerFunc) *R
 		Handlers: group.combineHandlers(handlers),
 		basePath: group.calculateAbsolutePath(relativePath),
 		engine:   group.engine,
+		host:     group.host,
 	}
 }
 
@@ -72,17 +91,40 @@ func (group *RouterGroup) BasePath() string {
 }
 
 func (group *RouterGroup) handle(httpMethod, relativePath string, handlers HandlersChain) IRoutes {
+	return group.handleWithBefore(httpMethod, relativePath, nil, handlers)
+}
+
+// handleWithBefore is handle, but with before spliced in ahead of the
+// group's own inherited Handlers instead of after them, for HandleBefore.
+func (group *RouterGroup) handleWithBefore(httpMethod, relativePath string, before, handlers HandlersChain) IRoutes {
 	// 将 group.basePath 和 relativePath 加起来得到最终的路径
 	absolutePath := group.calculateAbsolutePath(relativePath)
 	// 将现有的 Handlers 和 handlers 合并起来
-	handlers = group.combineHandlers(handlers)
-	// 将这个route加入到engine.tree
-	group.engine.addRoute(httpMethod, absolutePath, handlers)
+	combined := make(HandlersChain, 0, len(before)+len(group.Handlers)+len(handlers))
+	combined = append(combined, before...)
+	combined = append(combined, group.combineHandlers(handlers)...)
+	// 将这个route加入到engine.tree（或者host专属的tree，取决于该分组是否绑定了host）
+	group.engine.addRouteForHost(group.host, httpMethod, absolutePath, combined)
+	group.lastRoute = &lastRegisteredRoute{method: httpMethod, absolutePath: absolutePath, handlers: combined}
 	// 返回
 	return group.returnObj()
 }
 
-
+// HandleBefore registers a route like Handle, but splices before ahead of
+// the group's own inherited Use middleware for this one route only, rather
+// than combineHandlers's usual middleware-then-handlers order. Use it for a
+// fast-path check (e.g. an auth short-circuit) that must run before a
+// group's shared middleware without moving that middleware out of the group
+// for every other route. Context.Next/Abort behave exactly as with any
+// other handler chain, and Routes() still reports the last entry of
+// handlers as the route's name, since before never touches the tail of the
+// chain.
+func (group *RouterGroup) HandleBefore(httpMethod, relativePath string, before HandlersChain, handlers ...HandlerFunc) IRoutes {
+	if matches, err := regexp.MatchString("^[A-Z]+$", httpMethod); !matches || err != nil {
+		panic("http method " + httpMethod + " is not valid")
+	}
+	return group.handleWithBefore(httpMethod, relativePath, before, handlers)
+}
 
 // Handle registers a new request handle and middleware with the given path and method.
 // The last handler should be the real handler, the other ones should be middleware that can and should be shared among different routes.
@@ -151,6 +193,16 @@ func (group *RouterGroup) Any(relativePath string, handlers ...HandlerFunc) IRou
 	return group.returnObj()
 }
 
+// Match registers a route that matches the given HTTP methods, e.g.
+// router.Match([]string{"POST", "PUT"}, "/upload", handler) instead of
+// repeating router.POST/router.PUT with the same handlers.
+func (group *RouterGroup) Match(methods []string, relativePath string, handlers ...HandlerFunc) IRoutes {
+	for _, method := range methods {
+		group.handle(method, relativePath, handlers)
+	}
+	return group.returnObj()
+}
+
 // StaticFile registers a single route in order to serve a single file of the local filesystem.
 // router.StaticFile("favicon.ico", "./resources/favicon.ico")
 func (group *RouterGroup) StaticFile(relativePath, filepath string) IRoutes {
@@ -170,7 +222,8 @@ func (group *RouterGroup) StaticFile(relativePath, filepath string) IRoutes {
 // of the Router's NotFound handler.
 // To use the operating system's file system implementation,
 // use :
-//     router.Static("/static", "/var/www")
+//
+//	router.Static("/static", "/var/www")
 func (group *RouterGroup) Static(relativePath, root string) IRoutes {
 	return group.StaticFS(relativePath, Dir(root, false))
 }
@@ -190,19 +243,15 @@ func (group *RouterGroup) StaticFS(relativePath string, fs http.FileSystem) IRou
 	return group.returnObj()
 }
 
-
-
-
 func (group *RouterGroup) createStaticHandler(relativePath string, fs http.FileSystem) HandlerFunc {
 
-
 	// absolutePath := joinPaths(group.basePath, relativePath)
 	absolutePath := group.calculateAbsolutePath(relativePath)
-	// http.StripPrefix用于过滤掉特定的url前缀 
+	// http.StripPrefix用于过滤掉特定的url前缀
 	fileServer := http.StripPrefix(absolutePath, http.FileServer(fs))
 
 	return func(c *Context) {
-		
+
 		if _, nolisting := fs.(*onlyfilesFS); nolisting {
 			c.Writer.WriteHeader(http.StatusNotFound)
 		}
@@ -221,10 +270,6 @@ func (group *RouterGroup) createStaticHandler(relativePath string, fs http.FileS
 	}
 }
 
-
-
-
-
 // 组成handler链式处理：
 // 	1. 生成一个新的handler切片
 // 	2. 把中间件的handler插入到头部
@@ -246,6 +291,113 @@ func (group *RouterGroup) combineHandlers(handlers HandlersChain) HandlersChain
 	return mergedHandlers
 }
 
+// NoRoute registers a fallback used when a request under this group's
+// basePath doesn't match any route. It takes precedence over the
+// engine-level NoRoute for any path under this group, and over a less
+// specific (shorter basePath) group's NoRoute. Useful e.g. for a JSON 404
+// under "/api" alongside an HTML 404 for the rest of the site.
+func (group *RouterGroup) NoRoute(handlers ...HandlerFunc) IRoutes {
+	combined := group.combineHandlers(handlers)
+	group.engine.groupNoRoute = append(group.engine.groupNoRoute, groupFallback{prefix: group.basePath, handlers: combined})
+	return group.returnObj()
+}
+
+// NoMethod registers a fallback used when HandleMethodNotAllowed is true
+// and a request under this group's basePath matches a path but not the
+// method. See NoRoute for the precedence rules.
+func (group *RouterGroup) NoMethod(handlers ...HandlerFunc) IRoutes {
+	combined := group.combineHandlers(handlers)
+	group.engine.groupNoMethod = append(group.engine.groupNoMethod, groupFallback{prefix: group.basePath, handlers: combined})
+	return group.returnObj()
+}
+
+// WithErrorBoundary installs a middleware, scoped to this group only, that
+// recovers a panic from any handler further down the chain and, after
+// c.Next() returns, calls handler with every error accumulated in c.Errors
+// (plus a recovered panic, wrapped as one). Routes registered on this group
+// after the call, and on any subgroup created afterward, get the boundary;
+// routes outside the group and routes registered before the call do not.
+func (group *RouterGroup) WithErrorBoundary(handler func(c *Context, errs []error)) *RouterGroup {
+	group.Use(func(c *Context) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				if err, ok := rec.(error); ok {
+					c.Error(err)
+				} else {
+					c.Error(fmt.Errorf("%v", rec))
+				}
+			}
+			if len(c.Errors) > 0 {
+				errs := make([]error, len(c.Errors))
+				for i, e := range c.Errors {
+					errs[i] = e
+				}
+				handler(c, errs)
+			}
+		}()
+		c.Next()
+	})
+	return group
+}
+
+// WithTrailingSlashRedirect overrides Engine.RedirectTrailingSlash for every
+// request whose path falls under this group's basePath, regardless of the
+// engine-wide setting. A subgroup's own override takes precedence over one
+// set on an ancestor group, the same longest-prefix-wins rule NoRoute and
+// NoMethod use. Pass false for a group that should 404 on a trailing-slash
+// mismatch while the rest of the engine keeps redirecting, or true for the
+// opposite.
+func (group *RouterGroup) WithTrailingSlashRedirect(enabled bool) *RouterGroup {
+	group.engine.groupTrailingSlash = append(group.engine.groupTrailingSlash, groupBoolOverride{prefix: group.basePath, value: enabled})
+	return group
+}
+
+// headResponseWriter wraps a ResponseWriter to discard the body while still
+// forwarding headers and the status code, for use by WithHEADAndOPTIONS.
+type headResponseWriter struct {
+	ResponseWriter
+}
+
+func (w *headResponseWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (w *headResponseWriter) WriteString(s string) (int, error) {
+	return len(s), nil
+}
+
+// WithHEADAndOPTIONS installs HEAD and OPTIONS as real tree entries (visible
+// in Engine.Routes()) for the route most recently registered on this group,
+// sharing its handler chain. HEAD runs the same handlers but discards the
+// response body; OPTIONS short-circuits with a 204 and an Allow header
+// listing this route's method alongside HEAD and OPTIONS. It must be called
+// right after the route it derives from, e.g.:
+//
+//	router.GET("/x", handler).WithHEADAndOPTIONS()
+func (group *RouterGroup) WithHEADAndOPTIONS() IRoutes {
+	last := group.lastRoute
+	if last == nil {
+		panic("gin: WithHEADAndOPTIONS must follow a route registration on this group")
+	}
+
+	headHandlers := make(HandlersChain, 0, len(last.handlers)+1)
+	headHandlers = append(headHandlers, func(c *Context) {
+		c.Writer = &headResponseWriter{ResponseWriter: c.Writer}
+	})
+	headHandlers = append(headHandlers, last.handlers...)
+	group.engine.addRouteForHost(group.host, http.MethodHead, last.absolutePath, headHandlers)
+
+	methods := []string{last.method, http.MethodHead, http.MethodOptions}
+	sort.Slice(methods, func(i, j int) bool { return canonicalMethodOrder[methods[i]] < canonicalMethodOrder[methods[j]] })
+	allow := strings.Join(methods, ", ")
+	group.engine.addRouteForHost(group.host, http.MethodOptions, last.absolutePath, HandlersChain{func(c *Context) {
+		c.Writer.Header().Set("Allow", allow)
+		c.AbortWithStatus(http.StatusNoContent)
+	}})
+
+	return group.returnObj()
+}
+
 func (group *RouterGroup) calculateAbsolutePath(relativePath string) string {
 	return joinPaths(group.basePath, relativePath)
 }
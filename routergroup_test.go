@@ -6,6 +6,7 @@ package gin
 
 import (
 	"net/http"
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -125,6 +126,37 @@ func TestRouterGroupTooManyHandlers(t *testing.T) {
 	})
 }
 
+func TestRouterGroupMatch(t *testing.T) {
+	router := New()
+	router.Match([]string{"POST", "PUT"}, "/upload", func(c *Context) {})
+
+	routes := router.Routes()
+	var found []string
+	for _, route := range routes {
+		if route.Path == "/upload" {
+			found = append(found, route.Method)
+		}
+	}
+	sort.Strings(found)
+	assert.Equal(t, []string{"POST", "PUT"}, found)
+
+	w := performRequest(router, "POST", "/upload")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "PUT", "/upload")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "GET", "/upload")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRouterGroupMatchConflictingPathsPanicPerMethod(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", func(c *Context) {})
+
+	assert.Panics(t, func() {
+		router.Match([]string{"GET"}, "/users/new", func(c *Context) {})
+	})
+}
+
 func TestRouterGroupBadMethod(t *testing.T) {
 	router := New()
 	assert.Panics(t, func() {
@@ -158,6 +190,58 @@ func TestRouterGroupPipeline(t *testing.T) {
 	testRoutesInterface(t, v1)
 }
 
+func TestRouterGroupHandleBeforeRunsAheadOfGroupMiddleware(t *testing.T) {
+	router := New()
+	var order []string
+
+	group := router.Group("/api")
+	group.Use(func(c *Context) {
+		order = append(order, "group-middleware")
+		c.Next()
+	})
+	group.HandleBefore("GET", "/fast", HandlersChain{func(c *Context) {
+		order = append(order, "before")
+		c.Next()
+	}}, func(c *Context) {
+		order = append(order, "handler")
+	})
+
+	w := performRequest(router, "GET", "/api/fast")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"before", "group-middleware", "handler"}, order)
+
+	routes := router.Routes()
+	found := false
+	for _, route := range routes {
+		if route.Path == "/api/fast" {
+			found = true
+			assert.Contains(t, route.Handler, "TestRouterGroupHandleBeforeRunsAheadOfGroupMiddleware")
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestRouterGroupHandleBeforeAbortShortCircuits(t *testing.T) {
+	router := New()
+	var order []string
+
+	group := router.Group("/api")
+	group.Use(func(c *Context) {
+		order = append(order, "group-middleware")
+		c.Next()
+	})
+	group.HandleBefore("GET", "/guarded", HandlersChain{func(c *Context) {
+		order = append(order, "before")
+		c.AbortWithStatus(http.StatusUnauthorized)
+	}}, func(c *Context) {
+		order = append(order, "handler")
+	})
+
+	w := performRequest(router, "GET", "/api/guarded")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, []string{"before"}, order)
+}
+
 func testRoutesInterface(t *testing.T, r IRoutes) {
 	handler := func(c *Context) {}
 	assert.Equal(t, r, r.Use(handler))
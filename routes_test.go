@@ -5,12 +5,17 @@
 package gin
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -74,7 +79,13 @@ func testRouteNotOK2(method string, t *testing.T) {
 	w := performRequest(router, method, "/test")
 
 	assert.False(t, passed)
-	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	if method == "OPTIONS" {
+		// HandleMethodNotAllowed gives OPTIONS the clean 204 + Allow
+		// treatment instead of the 405 body other methods get.
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	} else {
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	}
 }
 
 func TestRouterMethod(t *testing.T) {
@@ -182,6 +193,94 @@ func TestRouteRedirectTrailingSlash(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
+func TestRouteRedirectTrailingSlashMethodPreserving(t *testing.T) {
+	router := New()
+	router.RedirectTrailingSlash = true
+	router.RedirectMethodPreserving = true
+	router.GET("/path", func(c *Context) {})
+	router.POST("/path2", func(c *Context) {})
+
+	w := performRequest(router, "GET", "/path/")
+	assert.Equal(t, "/path", w.Header().Get("Location"))
+	assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+
+	w = performRequest(router, "POST", "/path2/")
+	assert.Equal(t, "/path2", w.Header().Get("Location"))
+	assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+}
+
+func TestRouteRedirectTrailingSlashRewrite(t *testing.T) {
+	router := New()
+	router.RedirectTrailingSlash = true
+	router.RedirectTrailingSlashRewrite = true
+
+	var gotMethod, gotBody string
+	router.POST("/path", func(c *Context) {
+		gotMethod = c.Request.Method
+		body, _ := c.GetRawData()
+		gotBody = string(body)
+		c.String(http.StatusOK, "ok")
+	})
+
+	req, _ := http.NewRequest("POST", "/path/", strings.NewReader("payload"))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Empty(t, w.Header().Get("Location"))
+	assert.Equal(t, "POST", gotMethod)
+	assert.Equal(t, "payload", gotBody)
+}
+
+func TestRouteGroupTrailingSlashOverride(t *testing.T) {
+	router := New()
+	router.RedirectTrailingSlash = true
+
+	api := router.Group("/api")
+	api.WithTrailingSlashRedirect(false)
+	api.GET("/users", func(c *Context) {})
+	api.POST("/items", func(c *Context) {})
+
+	router.GET("/web", func(c *Context) {})
+
+	// the "/api" group opted out of redirects, so a trailing-slash
+	// mismatch there 404s instead of following the engine default.
+	w := performRequest(router, "GET", "/api/users/")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequest(router, "POST", "/api/items/")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// everything outside "/api" keeps the engine-wide redirect behavior,
+	// preserving the GET-gets-301/other-methods-get-307 split.
+	w = performRequest(router, "GET", "/web/")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/web", w.Header().Get("Location"))
+}
+
+func TestRouteGroupTrailingSlashOverrideEnablesWhenEngineDefaultIsOff(t *testing.T) {
+	router := New()
+	router.RedirectTrailingSlash = false
+
+	web := router.Group("/web")
+	web.WithTrailingSlashRedirect(true)
+	web.GET("/page", func(c *Context) {})
+	web.POST("/submit", func(c *Context) {})
+
+	router.GET("/api/users", func(c *Context) {})
+
+	w := performRequest(router, "GET", "/web/page/")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/web/page", w.Header().Get("Location"))
+
+	w = performRequest(router, "POST", "/web/submit/")
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	assert.Equal(t, "/web/submit", w.Header().Get("Location"))
+
+	w = performRequest(router, "GET", "/api/users/")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
 func TestRouteRedirectFixedPath(t *testing.T) {
 	router := New()
 	router.RedirectFixedPath = true
@@ -209,6 +308,29 @@ func TestRouteRedirectFixedPath(t *testing.T) {
 	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
 }
 
+// TestRouteRedirectFixedPathPreservesParamCase checks that fixing the case
+// of a path's static segments for a redirect never touches the case of a
+// param or catch-all value: only /FILES, the static prefix, is wrong-case
+// here, so the redirect must fix that and leave Photos/IMG.JPG exactly as
+// uploaded.
+func TestRouteRedirectFixedPathPreservesParamCase(t *testing.T) {
+	router := New()
+	router.RedirectFixedPath = true
+
+	var gotParam string
+	router.GET("/files/*path", func(c *Context) {
+		gotParam = c.Param("path")
+	})
+
+	w := performRequest(router, "GET", "/FILES/Photos/IMG.JPG")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/files/Photos/IMG.JPG", w.Header().Get("Location"))
+
+	w = performRequest(router, "GET", w.Header().Get("Location"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "/Photos/IMG.JPG", gotParam)
+}
+
 // TestContextParamsGet tests that a parameter can be parsed from the URL.
 func TestRouteParamsByName(t *testing.T) {
 	name := ""
@@ -242,6 +364,54 @@ func TestRouteParamsByName(t *testing.T) {
 	assert.Equal(t, "/is/super/great", wild)
 }
 
+func TestRouteOptionalTrailingParam(t *testing.T) {
+	router := New()
+	var gotYear, gotMonth string
+	var monthPresent bool
+	router.GET("/articles/:year/:month?", func(c *Context) {
+		gotYear = c.Param("year")
+		gotMonth, monthPresent = c.Params.Get("month")
+	})
+
+	w := performRequest(router, "GET", "/articles/2024")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2024", gotYear)
+	assert.Equal(t, "", gotMonth)
+	assert.False(t, monthPresent)
+
+	w = performRequest(router, "GET", "/articles/2024/03")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2024", gotYear)
+	assert.Equal(t, "03", gotMonth)
+	assert.True(t, monthPresent)
+
+	w = performRequest(router, "GET", "/articles/2024/03/extra")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRouteOptionalTrailingParamAsOnlySegment(t *testing.T) {
+	router := New()
+	var gotYear string
+	router.GET("/:year?", func(c *Context) {
+		gotYear = c.Param("year")
+	})
+
+	w := performRequest(router, "GET", "/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "", gotYear)
+
+	w = performRequest(router, "GET", "/2024")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2024", gotYear)
+}
+
+func TestRouteOptionalTrailingParamMustFollowParamSegment(t *testing.T) {
+	router := New()
+	assert.Panics(t, func() {
+		router.GET("/articles/year?", func(c *Context) {})
+	})
+}
+
 // TestHandleStaticFile - ensure the static file handles properly
 func TestRouteStaticFile(t *testing.T) {
 	// SETUP file
@@ -300,6 +470,18 @@ func TestRouteStaticNoListing(t *testing.T) {
 	assert.NotContains(t, w.Body.String(), "gin.go")
 }
 
+// TestRouteStaticRejectsPathTraversal ensures a ".." segment in the
+// requested path can't escape the served root -- http.Dir cleans the path
+// before opening it, so this never reaches the filesystem outside root.
+func TestRouteStaticRejectsPathTraversal(t *testing.T) {
+	router := New()
+	router.Static("/static", "./testdata")
+
+	w := performRequest(router, "GET", "/static/../../../../../../etc/passwd")
+	assert.NotEqual(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "root:")
+}
+
 func TestRouterMiddlewareAndStatic(t *testing.T) {
 	router := New()
 	static := router.Group("/", func(c *Context) {
@@ -476,6 +658,337 @@ func TestRouteRawPathNoUnescape(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func TestEngineRemoveRoute(t *testing.T) {
+	route := New()
+	route.GET("/feature", func(c *Context) { c.String(http.StatusOK, "on") })
+
+	w := performRequest(route, "GET", "/feature")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.True(t, route.RemoveRoute("GET", "/feature"))
+
+	w = performRequest(route, "GET", "/feature")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	for _, r := range route.Routes() {
+		assert.NotEqual(t, "/feature", r.Path)
+	}
+
+	assert.False(t, route.RemoveRoute("GET", "/feature"))
+	assert.False(t, route.RemoveRoute("GET", "/never-registered"))
+}
+
+func performHostRequest(r http.Handler, method, path, host string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	req.Host = host
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestEngineHostRouting(t *testing.T) {
+	router := New()
+	router.GET("/", func(c *Context) { c.String(http.StatusOK, "default") })
+
+	api := router.Host("api.example.com")
+	api.GET("/ping", func(c *Context) { c.String(http.StatusOK, "api pong") })
+
+	admin := router.Host("*.admin.example.com")
+	admin.GET("/ping", func(c *Context) { c.String(http.StatusOK, "admin:"+c.Param("subdomain")) })
+
+	w := performHostRequest(router, "GET", "/ping", "api.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "api pong", w.Body.String())
+
+	w = performHostRequest(router, "GET", "/ping", "tenant1.admin.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "admin:tenant1", w.Body.String())
+
+	// a host-scoped path is not reachable under a host that doesn't match it.
+	w = performHostRequest(router, "GET", "/ping", "unknown.example.com")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// an unregistered host falls back to the default (non-host-scoped) routes.
+	w = performHostRequest(router, "GET", "/", "unknown.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "default", w.Body.String())
+
+	var found bool
+	for _, route := range router.Routes() {
+		if route.Host == "api.example.com" && route.Path == "/ping" {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestEngineNamedRouteURL(t *testing.T) {
+	route := New()
+	route.GET("/users/:id", func(c *Context) {}).Name("user.show")
+	route.GET("/files/*filepath", func(c *Context) {}).Name("file.show")
+
+	url, err := route.URL("user.show", map[string]string{"id": "42"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/42", url)
+
+	url, err = route.URL("user.show", map[string]string{"id": "a b"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/a%20b", url)
+
+	url, err = route.URL("file.show", map[string]string{"filepath": "a/b/c.txt"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/files/a/b/c.txt", url)
+
+	_, err = route.URL("user.show", map[string]string{})
+	assert.Error(t, err)
+
+	_, err = route.URL("unknown.route", nil)
+	assert.Error(t, err)
+}
+
+type fakeTracer struct {
+	started []string
+	ended   int
+}
+
+func (ft *fakeTracer) StartSpan(ctx context.Context, name string) (context.Context, func()) {
+	ft.started = append(ft.started, name)
+	return ctx, func() { ft.ended++ }
+}
+
+func TestEngineTracer(t *testing.T) {
+	tracer := &fakeTracer{}
+	route := New()
+	route.Tracer = tracer
+	route.GET("/users/:id", func(c *Context) {})
+
+	w := performRequest(route, "GET", "/users/42")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"/users/:id"}, tracer.started)
+	assert.Equal(t, 1, tracer.ended)
+}
+
+func TestEngineOnRequestMatchedRoute(t *testing.T) {
+	route := New()
+	var got RequestInfo
+	route.OnRequest(func(info RequestInfo) {
+		got = info
+	})
+	route.GET("/users/:id", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := performRequest(route, "GET", "/users/42")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "GET", got.Method)
+	assert.Equal(t, "/users/:id", got.FullPath)
+	assert.Equal(t, http.StatusOK, got.Status)
+}
+
+func TestEngineOnRequestFiresOnNotFound(t *testing.T) {
+	route := New()
+	var got RequestInfo
+	var called bool
+	route.OnRequest(func(info RequestInfo) {
+		called = true
+		got = info
+	})
+
+	w := performRequest(route, "GET", "/missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.True(t, called)
+	assert.Equal(t, "", got.FullPath)
+	assert.Equal(t, http.StatusNotFound, got.Status)
+}
+
+func TestRouteMethodNotAllowedAllowHeader(t *testing.T) {
+	route := New()
+	route.HandleMethodNotAllowed = true
+	route.GET("/path", func(c *Context) {})
+	route.POST("/path", func(c *Context) {})
+
+	w := performRequest(route, "PUT", "/path")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	allow := w.Header().Get("Allow")
+	assert.Contains(t, allow, "GET")
+	assert.Contains(t, allow, "POST")
+}
+
+func TestRouteHandleOPTIONS(t *testing.T) {
+	route := New()
+	route.HandleOPTIONS = true
+	route.GET("/path", func(c *Context) {})
+	route.POST("/path", func(c *Context) {})
+
+	w := performRequest(route, "OPTIONS", "/path")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	allow := w.Header().Get("Allow")
+	assert.Contains(t, allow, "GET")
+	assert.Contains(t, allow, "POST")
+
+	w = performRequest(route, "OPTIONS", "/unknown")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRouteMethodNotAllowedOPTIONS(t *testing.T) {
+	route := New()
+	route.HandleMethodNotAllowed = true
+	route.GET("/path", func(c *Context) {})
+	route.POST("/path", func(c *Context) {})
+
+	// OPTIONS gets the clean 204 + Allow treatment without HandleOPTIONS
+	// having to be set separately.
+	w := performRequest(route, "OPTIONS", "/path")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	allow := w.Header().Get("Allow")
+	assert.Contains(t, allow, "GET")
+	assert.Contains(t, allow, "POST")
+
+	// a user-registered OPTIONS handler for the path is still used instead.
+	called := false
+	route.OPTIONS("/path", func(c *Context) {
+		called = true
+		c.Status(http.StatusTeapot)
+	})
+	w = performRequest(route, "OPTIONS", "/path")
+	assert.True(t, called)
+	assert.Equal(t, http.StatusTeapot, w.Code)
+}
+
+func TestRouteMethodNotAllowedAllowHeaderOrder(t *testing.T) {
+	route := New()
+	route.HandleMethodNotAllowed = true
+	route.POST("/path", func(c *Context) {})
+	route.GET("/path", func(c *Context) {})
+	route.DELETE("/path", func(c *Context) {})
+
+	w := performRequest(route, "PUT", "/path")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, POST, DELETE", w.Header().Get("Allow"))
+}
+
+func TestEngineStatusRewriter(t *testing.T) {
+	route := New()
+	route.StatusRewriter(func(code int) int {
+		if code == http.StatusNoContent {
+			return http.StatusOK
+		}
+		return code
+	})
+	route.GET("/ping", func(c *Context) { c.Status(http.StatusNoContent) })
+
+	w := performRequest(route, "GET", "/ping")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestGroupNoRoute(t *testing.T) {
+	route := New()
+	route.NoRoute(func(c *Context) { c.String(http.StatusNotFound, "global 404") })
+
+	api := route.Group("/api")
+	api.NoRoute(func(c *Context) { c.JSON(http.StatusNotFound, H{"error": "not found"}) })
+
+	w := performRequest(route, "GET", "/api/missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, `{"error":"not found"}`, w.Body.String())
+
+	w = performRequest(route, "GET", "/missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "global 404", w.Body.String())
+}
+
+func TestGroupNoMethod(t *testing.T) {
+	route := New()
+	route.HandleMethodNotAllowed = true
+
+	api := route.Group("/api")
+	api.GET("/widgets", func(c *Context) {})
+	api.NoMethod(func(c *Context) { c.JSON(http.StatusMethodNotAllowed, H{"error": "method not allowed"}) })
+
+	w := performRequest(route, "POST", "/api/widgets")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, `{"error":"method not allowed"}`, w.Body.String())
+}
+
+func TestContextFullPath(t *testing.T) {
+	var got string
+	route := New()
+	route.GET("/user/:id/posts/*filepath", func(c *Context) {
+		got = c.FullPath()
+	})
+
+	w := performRequest(route, "GET", "/user/42/posts/hello/world")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "/user/:id/posts/*filepath", got)
+}
+
+func TestContextFullPathNoRoute(t *testing.T) {
+	var got string
+	route := New()
+	route.NoRoute(func(c *Context) {
+		got = c.FullPath()
+	})
+
+	w := performRequest(route, "GET", "/nope")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "", got)
+}
+
+func TestRouteAPIErrorModeNotFound(t *testing.T) {
+	route := New()
+	route.APIErrorMode = true
+
+	w := performRequest(route, "GET", "/notfound")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"code":404,"message":"not found"}`, w.Body.String())
+}
+
+func TestRouteAPIErrorModeMethodNotAllowed(t *testing.T) {
+	route := New()
+	route.APIErrorMode = true
+	route.HandleMethodNotAllowed = true
+	route.POST("/path", func(c *Context) {})
+
+	w := performRequest(route, "GET", "/path")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"code":405,"message":"method not allowed"}`, w.Body.String())
+}
+
+func TestRouteSetDefault404(t *testing.T) {
+	route := New()
+	route.SetDefault404("application/json; charset=utf-8", []byte(`{"error":"not found"}`))
+
+	w := performRequest(route, "GET", "/notfound")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"error":"not found"}`, w.Body.String())
+}
+
+func TestRouteSetDefault405(t *testing.T) {
+	route := New()
+	route.HandleMethodNotAllowed = true
+	route.SetDefault405("application/json; charset=utf-8", []byte(`{"error":"method not allowed"}`))
+	route.POST("/path", func(c *Context) {})
+
+	w := performRequest(route, "GET", "/path")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"error":"method not allowed"}`, w.Body.String())
+}
+
+func TestRouteSetDefault404TakesPrecedenceOverAPIErrorMode(t *testing.T) {
+	route := New()
+	route.APIErrorMode = true
+	route.SetDefault404("text/plain; charset=utf-8", []byte("nope"))
+
+	w := performRequest(route, "GET", "/notfound")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "nope", w.Body.String())
+}
+
 func TestRouteServeErrorWithWriteHeader(t *testing.T) {
 	route := New()
 	route.Use(func(c *Context) {
@@ -487,3 +1000,228 @@ func TestRouteServeErrorWithWriteHeader(t *testing.T) {
 	assert.Equal(t, 421, w.Code)
 	assert.Equal(t, 0, w.Body.Len())
 }
+
+// TestRouteLoggerObservesAbortStatusAfterNext mirrors a logging middleware
+// that runs code after c.Next() returns: it must be able to tell an
+// aborted chain from a completed one and read the exact status an
+// aborting auth handler set, without the downstream handler running.
+func TestRouteLoggerObservesAbortStatusAfterNext(t *testing.T) {
+	router := New()
+	var sawAborted bool
+	var sawStatus int
+	var handlerRan bool
+
+	router.Use(func(c *Context) {
+		c.Next()
+		sawAborted = c.IsAborted()
+		sawStatus = c.Writer.Status()
+	})
+	router.GET("/admin", func(c *Context) {
+		c.AbortWithStatus(http.StatusForbidden)
+	}, func(c *Context) {
+		handlerRan = true
+	})
+
+	w := performRequest(router, "GET", "/admin")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.True(t, sawAborted)
+	assert.Equal(t, http.StatusForbidden, sawStatus)
+	assert.False(t, handlerRan)
+}
+
+// TestRouteLoggerObservesSizeAndWrittenAfterNext mirrors a logging
+// middleware reading the final response size and written-ness of a normal
+// (non-aborted) request off c.Writer, the way Logger's own ResponseWriter
+// access works, after downstream handlers have written their body.
+func TestRouteLoggerObservesSizeAndWrittenAfterNext(t *testing.T) {
+	router := New()
+	var sawStatus, sawSize int
+	var sawWritten bool
+
+	router.Use(func(c *Context) {
+		c.Next()
+		sawStatus = c.Writer.Status()
+		sawSize = c.Writer.Size()
+		sawWritten = c.Writer.Written()
+	})
+	router.GET("/hello", func(c *Context) {
+		c.String(http.StatusOK, "hello world")
+	})
+
+	w := performRequest(router, "GET", "/hello")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusOK, sawStatus)
+	assert.Equal(t, len("hello world"), sawSize)
+	assert.True(t, sawWritten)
+}
+
+// TestRouteHijackForWebSocketUpgrade exercises c.Writer.Hijack() over a real
+// TCP connection the way a WebSocket library would: take over the
+// connection, write the upgrade response by hand, then read and echo back
+// a frame. It only covers hijacking, not the actual WebSocket wire format.
+func TestRouteHijackForWebSocketUpgrade(t *testing.T) {
+	router := New()
+	router.GET("/ws", func(c *Context) {
+		conn, bufrw, err := c.Writer.Hijack()
+		if err != nil {
+			c.String(http.StatusInternalServerError, "hijack failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		bufrw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		bufrw.Flush()
+
+		frame, err := bufrw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		bufrw.WriteString(frame)
+		bufrw.Flush()
+	})
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	req, _ := http.NewRequest("GET", "/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	if err := req.Write(conn); err != nil {
+		t.Fatalf("write request: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	statusLine, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("read status line: %v", err)
+	}
+	assert.Contains(t, statusLine, "101")
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read headers: %v", err)
+		}
+		if line == "\r\n" {
+			break
+		}
+	}
+
+	if _, err := conn.Write([]byte("hello frame\n")); err != nil {
+		t.Fatalf("write frame: %v", err)
+	}
+
+	echoed, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("read echoed frame: %v", err)
+	}
+	assert.Equal(t, "hello frame\n", echoed)
+}
+
+func TestWithHEADAndOPTIONS(t *testing.T) {
+	router := New()
+	router.GET("/x", func(c *Context) {
+		c.String(http.StatusOK, "hello")
+	}).WithHEADAndOPTIONS()
+
+	routes := router.Routes()
+	var methods []string
+	for _, route := range routes {
+		if route.Path == "/x" {
+			methods = append(methods, route.Method)
+		}
+	}
+	assert.ElementsMatch(t, []string{"GET", "HEAD", "OPTIONS"}, methods)
+
+	w := performRequest(router, "GET", "/x")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hello", w.Body.String())
+
+	w = performRequest(router, "HEAD", "/x")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "", w.Body.String())
+
+	w = performRequest(router, "OPTIONS", "/x")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "GET, HEAD, OPTIONS", w.Header().Get("Allow"))
+}
+
+func TestRouterGroupErrorBoundaryHandlesPanicAndErrors(t *testing.T) {
+	router := New()
+
+	var caught []error
+	api := router.Group("/api")
+	api.WithErrorBoundary(func(c *Context, errs []error) {
+		caught = errs
+		c.Status(http.StatusInternalServerError)
+	})
+	api.GET("/panics", func(c *Context) {
+		panic(errors.New("boom"))
+	})
+	api.GET("/errors", func(c *Context) {
+		c.Error(errors.New("first"))
+		c.Error(errors.New("second"))
+	})
+
+	w := performRequest(router, "GET", "/api/panics")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	if assert.Len(t, caught, 1) {
+		assert.EqualError(t, caught[0], "boom")
+	}
+
+	caught = nil
+	w = performRequest(router, "GET", "/api/errors")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Len(t, caught, 2)
+}
+
+func TestRouterGroupErrorBoundaryScopedToGroup(t *testing.T) {
+	router := New()
+
+	called := false
+	api := router.Group("/api")
+	api.WithErrorBoundary(func(c *Context, errs []error) {
+		called = true
+	})
+	api.GET("/errors", func(c *Context) {
+		c.Error(errors.New("boom"))
+	})
+	router.GET("/outside", func(c *Context) {
+		c.Error(errors.New("boom"))
+	})
+
+	w := performRequest(router, "GET", "/outside")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, called)
+
+	performRequest(router, "GET", "/api/errors")
+	assert.True(t, called)
+}
+
+func TestRemoveMatrixParams(t *testing.T) {
+	router := New()
+	router.RemoveMatrixParams = true
+	router.GET("/foo/:id/bar", func(c *Context) {
+		c.String(http.StatusOK, c.Param("id"))
+	})
+
+	w := performRequest(router, "GET", "/foo;jsessionid=1234/42/bar")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "42", w.Body.String())
+}
+
+func TestRemoveMatrixParamsDefaultOff(t *testing.T) {
+	router := New()
+	router.GET("/foo/:id/bar", func(c *Context) {
+		c.String(http.StatusOK, c.Param("id"))
+	})
+
+	w := performRequest(router, "GET", "/foo;jsessionid=1234/42/bar")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
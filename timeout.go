@@ -0,0 +1,162 @@
+// Copyright 2014 Manu Martinez-Almeida.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// timeoutWriter sits between the handler chain and the real ResponseWriter
+// so TimeoutMiddleware can claim the response the instant the deadline
+// fires without racing a late-finishing handler on the same connection.
+//
+// Header() returns a header map private to timeoutWriter -- the handler
+// mutates it freely, and it's only ever copied onto the real writer's
+// header (under mu) the moment something actually commits a response,
+// mirroring the approach net/http.TimeoutHandler uses for the same reason.
+// Once the deadline side commits first, every later write attempt through
+// timeoutWriter is silently dropped; once the handler commits first, the
+// deadline side writes nothing and the handler's writes go through.
+type timeoutWriter struct {
+	ResponseWriter
+	h http.Header
+
+	mu          sync.Mutex
+	timedOut    bool
+	wroteHeader bool
+	status      int
+}
+
+func newTimeoutWriter(rw ResponseWriter) *timeoutWriter {
+	return &timeoutWriter{ResponseWriter: rw, h: make(http.Header), status: http.StatusOK}
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+// commitLocked copies the buffered header onto the real writer and writes
+// code. Callers must hold tw.mu and have already checked tw.wroteHeader.
+func (tw *timeoutWriter) commitLocked(code int) {
+	tw.wroteHeader = true
+	dst := tw.ResponseWriter.Header()
+	for k, vv := range tw.h {
+		dst[k] = vv
+	}
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+// WriteHeader only records the intended status code, the same deferred-commit
+// contract (*responseWriter).WriteHeader follows: it doesn't copy tw.h onto
+// the real writer or call through to it, so a header set after WriteHeader
+// (gin's own render path calls c.Status before WriteContentType) still makes
+// it into tw.h before commitLocked eventually copies it. A later WriteHeader
+// call before anything commits just replaces the recorded status, matching
+// the "last call wins" behavior handlers can already rely on.
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.status = code
+}
+
+func (tw *timeoutWriter) WriteHeaderNow() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	if !tw.wroteHeader {
+		tw.commitLocked(tw.status)
+	}
+	tw.ResponseWriter.WriteHeaderNow()
+}
+
+func (tw *timeoutWriter) Write(data []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return len(data), nil
+	}
+	if !tw.wroteHeader {
+		tw.commitLocked(tw.status)
+	}
+	return tw.ResponseWriter.Write(data)
+}
+
+func (tw *timeoutWriter) WriteString(s string) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return len(s), nil
+	}
+	if !tw.wroteHeader {
+		tw.commitLocked(tw.status)
+	}
+	return tw.ResponseWriter.WriteString(s)
+}
+
+// claim takes ownership of the response for the deadline side, writing code
+// straight to the real writer and locking out every later handler write.
+// It fails if the handler already committed a response first.
+func (tw *timeoutWriter) claim(code int) bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return false
+	}
+	tw.timedOut = true
+	tw.ResponseWriter.WriteHeader(code)
+	tw.ResponseWriter.WriteHeaderNow()
+	return true
+}
+
+// TimeoutMiddleware returns a middleware that derives a context with a d
+// deadline from the request's context and attaches it back to c.Request, so
+// downstream calls that honor ctx.Done() (database queries, outgoing HTTP
+// requests, ...) are canceled along with it.
+//
+// If the rest of the handler chain hasn't finished by the deadline, the
+// middleware writes a 503 right away instead of waiting for it. Whichever
+// side commits a response first wins: the handler's writes go through a
+// private header map until then, so the loser never touches the real
+// ResponseWriter and can't race the winner on it.
+//
+// The handler chain still runs to completion on its own goroutine even
+// after the 503 is written -- it is up to it to notice the canceled context
+// and return promptly, since c.index (and therefore c.Next()) must only
+// ever be touched by that one goroutine. This middleware itself doesn't
+// return until the chain does, so make sure slow calls inside the handler
+// are context-aware and actually stop at the deadline; otherwise a timed
+// out request keeps its goroutine (though not the client) waiting.
+func TimeoutMiddleware(d time.Duration) HandlerFunc {
+	return func(c *Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		tw := newTimeoutWriter(c.Writer)
+		c.Writer = tw
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			c.Next()
+		}()
+
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			tw.claim(http.StatusServiceUnavailable)
+			<-done
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2014 Manu Martinez-Almeida.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutMiddlewareLetsFastHandlersThrough(t *testing.T) {
+	router := New()
+	router.Use(TimeoutMiddleware(100 * time.Millisecond))
+	router.GET("/fast", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := performRequest(router, "GET", "/fast")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok", w.Body.String())
+}
+
+// TestTimeoutMiddlewarePreservesHeadersSetAfterStatus reproduces a bug where
+// WriteHeader committed the response (including whatever headers were
+// already in tw.h) on its first call, so a header set afterwards -- like the
+// Content-Type c.String sets via WriteContentType only after calling
+// c.Status -- never reached the real ResponseWriter.
+func TestTimeoutMiddlewarePreservesHeadersSetAfterStatus(t *testing.T) {
+	router := New()
+	router.Use(TimeoutMiddleware(100 * time.Millisecond))
+	router.GET("/fast", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := performRequest(router, "GET", "/fast")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+}
+
+// TestTimeoutMiddlewareLastStatusWins confirms a handler calling c.Status
+// more than once before writing a body keeps the usual "last call wins"
+// behavior through the timeout writer, not just the first.
+func TestTimeoutMiddlewareLastStatusWins(t *testing.T) {
+	router := New()
+	router.Use(TimeoutMiddleware(100 * time.Millisecond))
+	router.GET("/fast", func(c *Context) {
+		c.Status(http.StatusAccepted)
+		c.String(http.StatusCreated, "ok")
+	})
+
+	w := performRequest(router, "GET", "/fast")
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestTimeoutMiddlewareAbortsSlowHandlers(t *testing.T) {
+	router := New()
+	router.Use(TimeoutMiddleware(20 * time.Millisecond))
+	handlerReturned := make(chan struct{})
+	router.GET("/slow", func(c *Context) {
+		defer close(handlerReturned)
+		select {
+		case <-c.Request.Context().Done():
+		case <-time.After(time.Second):
+			t.Error("handler's context was never canceled")
+		}
+		// Give TimeoutMiddleware's own (immediate) claim a moment to land
+		// before this handler tries to write its own, now-stale response.
+		time.Sleep(20 * time.Millisecond)
+		c.String(http.StatusOK, "too late")
+	})
+
+	req := httptest.NewRequest("GET", "/slow", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	select {
+	case <-handlerReturned:
+	case <-time.After(time.Second):
+		t.Fatal("handler never returned after its context was canceled")
+	}
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.NotContains(t, w.Body.String(), "too late")
+}
@@ -5,7 +5,9 @@
 package gin
 
 import (
+	"math"
 	"net/url"
+	"regexp"
 	"strings"
 	"unicode"
 )
@@ -43,6 +45,28 @@ func (ps Params) ByName(name string) (va string) {
 	return
 }
 
+// Exists reports whether a Param with the given name is present, using the
+// same exact, case-sensitive match as Get/ByName.
+func (ps Params) Exists(name string) bool {
+	_, ok := ps.Get(name)
+	return ok
+}
+
+// GetFold is Get's case-insensitive counterpart: it matches name against
+// each key with strings.EqualFold instead of ==. Intended for defensive
+// code that doesn't control how the route was registered (e.g. a ":ID"
+// param looked up as "id"); Get/ByName stay the default for the routing hot
+// path, where registration and lookup casing are under the same author's
+// control.
+func (ps Params) GetFold(name string) (string, bool) {
+	for _, entry := range ps {
+		if strings.EqualFold(entry.Key, name) {
+			return entry.Value, true
+		}
+	}
+	return "", false
+}
+
 //方法对应的路由树
 type methodTree struct {
 	method string //方法
@@ -70,7 +94,7 @@ func min(a, b int) int {
 }
 
 //计算该path下路由参数的数量
-func countParams(path string) uint8 {
+func countParams(path string) uint16 {
 	var n uint
 	for i := 0; i < len(path); i++ {
 		if path[i] != ':' && path[i] != '*' {
@@ -78,10 +102,10 @@ func countParams(path string) uint8 {
 		}
 		n++
 	}
-	if n >= 255 {
-		return 255
+	if n >= math.MaxUint16 {
+		return math.MaxUint16
 	}
-	return uint8(n)
+	return uint16(n)
 }
 
 type nodeType uint8
@@ -101,8 +125,17 @@ type node struct {
 	handlers  HandlersChain //处理该节点的方法的链
 	priority  uint32        //优先级。根据节点下的节点数量赋值
 	nType     nodeType      //节点类型
-	maxParams uint8         //节点下路由的最大路由参数数量
+	maxParams uint16        //节点下路由的最大路由参数数量
 	wildChild bool          //是否为一个路由参数的节点的父节点 如果是 那么该节点下一层不能有路由参数节点外其他节点存在
+	fullPath  string        //该节点注册时的完整路由模板，例如`/users/:id/posts/*filepath`
+
+	// paramRegexp, when non-nil, constrains a param node: the matched
+	// segment must satisfy the regexp or the lookup falls through to
+	// 404/405, e.g. for a route registered as "/users/:id(\d+)".
+	// nil for the common (unconstrained) case, so the fast path stays
+	// allocation-free. The pattern itself must not contain ':' or '*',
+	// since those still terminate wildcard-name scanning in insertChild.
+	paramRegexp *regexp.Regexp
 }
 
 // increments priority of the given child and reorders if necessary.
@@ -247,6 +280,12 @@ func (n *node) addRoute(path string, handlers HandlersChain) {
 						pathSeg = strings.SplitN(path, "/", 2)[0]
 					}
 					prefix := fullPath[:strings.Index(fullPath, pathSeg)] + n.path
+					// Note: this also rejects registering a constrained
+					// wildcard (":id(\d+)") alongside a differently named
+					// one (":name") at the same path segment — the radix
+					// tree can only hold a single wildcard child per node,
+					// constrained or not, so such routes must be told apart
+					// by a static prefix instead.
 					panic("'" + pathSeg +
 						"' in new path '" + fullPath +
 						"' conflicts with existing wildcard '" + n.path +
@@ -300,6 +339,7 @@ func (n *node) addRoute(path string, handlers HandlersChain) {
 					panic("handlers are already registered for path '" + fullPath + "'")
 				}
 				n.handlers = handlers
+				n.fullPath = fullPath
 			}
 			return
 		}
@@ -315,7 +355,7 @@ func (n *node) addRoute(path string, handlers HandlersChain) {
 //就例如原本有一条/helloworld路由 新添加一条/hellogo路由
 //这里的n不是/hello这个节点 而是在/hello下面新开的一个空节点
 //(自我感觉这种做法有点怪)
-func (n *node) insertChild(numParams uint8, path string, fullPath string, handlers HandlersChain) {
+func (n *node) insertChild(numParams uint16, path string, fullPath string, handlers HandlersChain) {
 	var offset int // already handled bytes of the path
 
 	//这个loop是解析参数路由
@@ -364,9 +404,25 @@ func (n *node) insertChild(numParams uint8, path string, fullPath string, handle
 				offset = i
 			}
 
+			// optional inline constraint, e.g. ":id(\d+)" — the name is
+			// everything before '(', the constraint is compiled once here
+			// and checked against the matched segment in getValue.
+			var paramRegexp *regexp.Regexp
+			name := path[i+1 : end]
+			if paren := strings.IndexByte(name, '('); paren >= 0 {
+				if name[len(name)-1] != ')' {
+					panic("wildcard constraint must be closed with ')' in path '" + fullPath + "'")
+				}
+				if paren == 0 {
+					panic("wildcards must be named with a non-empty name in path '" + fullPath + "'")
+				}
+				paramRegexp = regexp.MustCompile(name[paren+1 : len(name)-1])
+			}
+
 			child := &node{
-				nType:     param,
-				maxParams: numParams,
+				nType:       param,
+				maxParams:   numParams,
+				paramRegexp: paramRegexp,
 			}
 			n.children = []*node{child}
 			n.wildChild = true
@@ -438,6 +494,7 @@ func (n *node) insertChild(numParams uint8, path string, fullPath string, handle
 				maxParams: 1,
 				handlers:  handlers,
 				priority:  1,
+				fullPath:  fullPath,
 			}
 			n.children = []*node{child}
 
@@ -451,6 +508,95 @@ func (n *node) insertChild(numParams uint8, path string, fullPath string, handle
 	//比如`/welcome/:name`中的`:name` 或`/welcome/:name/hello`中的`/hello`
 	n.path = path[offset:]
 	n.handlers = handlers
+	n.fullPath = fullPath
+}
+
+// removeRoute removes the handlers registered for the given literal route
+// path (the exact string originally passed to addRoute, e.g. "/users/:id").
+// It clears the handlers on the matching node and prunes any child branch
+// that becomes empty as a result, keeping wildChild/indices/priority
+// consistent. It returns false if the path was never registered.
+// Not concurrency-safe!
+//移除路由 path必须和注册时的字面量一致，比如":id"而不是具体的参数值
+func (n *node) removeRoute(path string) bool {
+	if path == "" {
+		if n.handlers == nil {
+			return false
+		}
+		n.handlers = nil
+		n.fullPath = ""
+		n.priority--
+		return true
+	}
+
+	if len(path) < len(n.path) || path[:len(n.path)] != n.path {
+		return false
+	}
+	path = path[len(n.path):]
+
+	if path == "" {
+		if n.handlers == nil {
+			return false
+		}
+		n.handlers = nil
+		n.fullPath = ""
+		n.priority--
+		return true
+	}
+
+	if n.wildChild {
+		child := n.children[0]
+		if !child.removeRoute(path) {
+			return false
+		}
+		n.priority--
+		n.pruneChild(0)
+		return true
+	}
+
+	// insertChild links a param node's "/..." child without an indices entry.
+	if n.nType == param && n.indices == "" && len(n.children) == 1 {
+		child := n.children[0]
+		if !child.removeRoute(path) {
+			return false
+		}
+		n.priority--
+		if child.handlers == nil && len(child.children) == 0 {
+			n.children = nil
+		}
+		return true
+	}
+
+	c := path[0]
+	for i := 0; i < len(n.indices); i++ {
+		if n.indices[i] == c {
+			child := n.children[i]
+			if !child.removeRoute(path) {
+				return false
+			}
+			n.priority--
+			n.pruneChild(i)
+			return true
+		}
+	}
+	return false
+}
+
+// pruneChild drops children[i] from n when it no longer has handlers nor
+// children of its own, keeping wildChild/indices in sync with children.
+//如果子节点既没有handlers也没有子节点了，就把它从父节点上摘掉
+func (n *node) pruneChild(i int) {
+	child := n.children[i]
+	if child.handlers != nil || len(child.children) > 0 {
+		return
+	}
+
+	n.children = append(n.children[:i], n.children[i+1:]...)
+	if n.wildChild {
+		n.wildChild = false
+	} else {
+		n.indices = n.indices[:i] + n.indices[i+1:]
+	}
 }
 
 // getValue returns the handle registered with the given path (key). The values of
@@ -458,10 +604,27 @@ func (n *node) insertChild(numParams uint8, path string, fullPath string, handle
 // If no handle can be found, a TSR (trailing slash redirect) recommendation is
 // made if a handle exists with an extra (without the) trailing slash for the
 // given path.
+//
+// Match precedence at each path segment, highest first: a static child
+// (matched via n.indices), then a param child (":name"), then a catch-all
+// child ("*name"). In practice this is never actually exercised as a
+// runtime choice, because a node can only ever have a static set of
+// indexed children *or* a single wildChild (param/catch-all) -- never
+// both. addRoute enforces that exclusivity at registration time: adding a
+// wildcard under a node that already has static children panics ("wildcard
+// route conflicts with existing children"), and adding a static or
+// differently-named wildcard sibling under a node that's already a
+// wildChild panics just the same ("conflicts with existing wildcard"),
+// regardless of which route was registered first. So "/users/new" (static)
+// and "/users/:id" (param) can never coexist at that segment at all --
+// whichever is registered second panics immediately. Telling them apart
+// requires a distinct static prefix instead (e.g. "/users/new" vs
+// "/admin/users/:id"), or the ":id(pattern)" constraint syntax if both
+// are truly meant to share one wildcard.
 //根据uri和一些参数得到handles
 //tsr是树上的path是否以`/`结尾
 //unescape是是否需要QueryEscape解码
-func (n *node) getValue(path string, po Params, unescape bool) (handlers HandlersChain, p Params, tsr bool) {
+func (n *node) getValue(path string, po Params, unescape bool) (handlers HandlersChain, p Params, tsr bool, fullPath string) {
 	p = po
 walk: // Outer loop for walking the tree
 	for {
@@ -515,11 +678,21 @@ walk: // Outer loop for walking the tree
 					if cap(p) < int(n.maxParams) {
 						p = make(Params, 0, n.maxParams)
 					}
+					val := path[:end]
+					// a constrained param (":id(\d+)") that doesn't match
+					// falls through to 404/405, same as a missing route.
+					if n.paramRegexp != nil && !n.paramRegexp.MatchString(val) {
+						return
+					}
+
 					//得到参数，赋值给返回的param
 					i := len(p)
 					p = p[:i+1] // expand slice within preallocated capacity
-					p[i].Key = n.path[1:]
-					val := path[:end]
+					key := n.path[1:]
+					if paren := strings.IndexByte(key, '('); paren >= 0 {
+						key = key[:paren]
+					}
+					p[i].Key = key
 					//如果是QueryEscape 就解码 反之不用 直接赋值
 					if unescape {
 						var err error
@@ -550,6 +723,7 @@ walk: // Outer loop for walking the tree
 
 					//参数节点后面没有path可解析了
 					if handlers = n.handlers; handlers != nil {
+						fullPath = n.fullPath
 						return
 					}
 
@@ -582,10 +756,16 @@ walk: // Outer loop for walking the tree
 					}
 
 					handlers = n.handlers
+					fullPath = n.fullPath
 					return
 
 				default:
-					panic("invalid node type")
+					// Corrupted tree (e.g. a future dynamic-remove bug) --
+					// rather than panicking the request goroutine, treat
+					// this branch as a miss (-> 404) and log it, so one
+					// bad node doesn't take down an otherwise fine request.
+					debugPrint("getValue: node %q has invalid type %d, treating as not found", n.path, n.nType)
+					return
 				}
 			}
 		} else if path == n.path {
@@ -593,6 +773,7 @@ walk: // Outer loop for walking the tree
 			// Check if this node has a handle registered.
 			//如果path一样了，那么证明当前节点就是要寻找的节点
 			if handlers = n.handlers; handlers != nil {
+				fullPath = n.fullPath
 				return
 			}
 
@@ -706,7 +887,11 @@ func (n *node) findCaseInsensitivePath(path string, fixTrailingSlash bool) (ciPa
 				return append(ciPath, path...), true
 
 			default:
-				panic("invalid node type")
+				// Same resilience policy as getValue: a corrupted node
+				// type shouldn't panic the request goroutine, just fail
+				// this lookup.
+				debugPrint("findCaseInsensitivePath: node %q has invalid type %d, treating as not found", n.path, n.nType)
+				return ciPath, false
 			}
 		} else {
 			// We should have reached the node containing the handle.
@@ -35,7 +35,7 @@ func checkRequests(t *testing.T, tree *node, requests testRequests, unescapes ..
 	}
 
 	for _, request := range requests {
-		handler, ps, _ := tree.getValue(request.path, nil, unescape)
+		handler, ps, _, _ := tree.getValue(request.path, nil, unescape)
 
 		if handler == nil {
 			if !request.nilHandler {
@@ -76,8 +76,8 @@ func checkPriorities(t *testing.T, n *node) uint32 {
 	return prio
 }
 
-func checkMaxParams(t *testing.T, n *node) uint8 {
-	var maxParams uint8
+func checkMaxParams(t *testing.T, n *node) uint16 {
+	var maxParams uint16
 	for i := range n.children {
 		params := checkMaxParams(t, n.children[i])
 		if params > maxParams {
@@ -98,15 +98,82 @@ func checkMaxParams(t *testing.T, n *node) uint8 {
 	return maxParams
 }
 
+func TestParamsExactMatch(t *testing.T) {
+	ps := Params{{Key: "ID", Value: "42"}}
+
+	if !ps.Exists("ID") {
+		t.Error("Exists(\"ID\") = false, want true")
+	}
+	if ps.Exists("id") {
+		t.Error("Exists(\"id\") = true, want false (exact match is case-sensitive)")
+	}
+
+	if value, ok := ps.Get("ID"); !ok || value != "42" {
+		t.Errorf("Get(\"ID\") = %q, %t, want \"42\", true", value, ok)
+	}
+	if value, ok := ps.Get("id"); ok {
+		t.Errorf("Get(\"id\") = %q, %t, want \"\", false", value, ok)
+	}
+	if va := ps.ByName("id"); va != "" {
+		t.Errorf("ByName(\"id\") = %q, want \"\"", va)
+	}
+}
+
+func TestParamsGetFold(t *testing.T) {
+	ps := Params{{Key: "ID", Value: "42"}}
+
+	if value, ok := ps.GetFold("id"); !ok || value != "42" {
+		t.Errorf("GetFold(\"id\") = %q, %t, want \"42\", true", value, ok)
+	}
+	if _, ok := ps.GetFold("missing"); ok {
+		t.Error("GetFold(\"missing\") = true, want false")
+	}
+}
+
 func TestCountParams(t *testing.T) {
 	if countParams("/path/:param1/static/*catch-all") != 2 {
 		t.Fail()
 	}
-	if countParams(strings.Repeat("/:param", 256)) != 255 {
+	if countParams(strings.Repeat("/:param", 256)) != 256 {
 		t.Fail()
 	}
 }
 
+// TestTreeHighParamCountPreallocatesExactly registers a route with more
+// params than fit in a uint8 and checks the Params slice getValue hands
+// back is preallocated with exactly enough capacity, not truncated or
+// grown after the fact.
+func TestTreeHighParamCountPreallocatesExactly(t *testing.T) {
+	const paramCount = 300
+	var pathBuilder strings.Builder
+	var want Params
+	for i := 0; i < paramCount; i++ {
+		name := fmt.Sprintf("p%d", i)
+		pathBuilder.WriteString("/:" + name)
+		want = append(want, Param{Key: name, Value: name})
+	}
+	path := pathBuilder.String()
+
+	tree := &node{}
+	tree.addRoute(path, fakeHandler(path))
+
+	var reqPath strings.Builder
+	for _, p := range want {
+		reqPath.WriteString("/" + p.Value)
+	}
+
+	handler, ps, _, _ := tree.getValue(reqPath.String(), nil, false)
+	if handler == nil {
+		t.Fatalf("didn't match path %q", reqPath.String())
+	}
+	if cap(ps) != paramCount {
+		t.Errorf("Params preallocated with cap %d, want exactly %d", cap(ps), paramCount)
+	}
+	if !reflect.DeepEqual(Params(want), ps) {
+		t.Errorf("params mismatch: got %v, want %v", ps, want)
+	}
+}
+
 func TestTreeAddAndGet(t *testing.T) {
 	tree := &node{}
 
@@ -145,6 +212,89 @@ func TestTreeAddAndGet(t *testing.T) {
 	checkMaxParams(t, tree)
 }
 
+func TestTreeWildcardConstraint(t *testing.T) {
+	tree := &node{}
+	tree.addRoute("/users/:id(^[0-9]+$)", fakeHandler("/users/:id(^[0-9]+$)"))
+
+	checkRequests(t, tree, testRequests{
+		{"/users/42", false, "/users/:id(^[0-9]+$)", Params{Param{"id", "42"}}},
+		{"/users/abc", true, "", Params{}},
+	})
+}
+
+func TestTreeRemoveRoute(t *testing.T) {
+	tree := &node{}
+
+	routes := [...]string{
+		"/hi",
+		"/contact",
+		"/co",
+		"/con",
+		"/doc/",
+		"/doc/go_faq.html",
+		"/doc/go1.html",
+		"/user/:name",
+		"/user/:name/profile",
+		"/files/*filepath",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route))
+	}
+
+	// removing a route that was never registered fails
+	if tree.removeRoute("/nope") {
+		t.Errorf("removeRoute should have failed for an unregistered path")
+	}
+
+	// a node whose children still hold routes only loses its own handlers
+	if !tree.removeRoute("/doc/") {
+		t.Errorf("removeRoute should have succeeded for '/doc/'")
+	}
+	checkRequests(t, tree, testRequests{
+		{"/doc/", true, "", nil},
+		{"/doc/go_faq.html", false, "/doc/go_faq.html", nil},
+	})
+
+	// removing the wildcard leaf leaves the shorter wildcard route intact
+	if !tree.removeRoute("/user/:name/profile") {
+		t.Errorf("removeRoute should have succeeded for '/user/:name/profile'")
+	}
+	checkRequests(t, tree, testRequests{
+		{"/user/gordon/profile", true, "", Params{Param{"name", "gordon"}}},
+		{"/user/gordon", false, "/user/:name", Params{Param{"name", "gordon"}}},
+	})
+
+	// removing the last route under a wildcard prunes the wildcard branch entirely
+	if !tree.removeRoute("/user/:name") {
+		t.Errorf("removeRoute should have succeeded for '/user/:name'")
+	}
+	for _, child := range tree.children {
+		if child.path == "user/" && child.wildChild {
+			t.Errorf("expected the wildcard branch under /user/ to be pruned")
+		}
+	}
+
+	// catch-all routes can be removed too
+	if !tree.removeRoute("/files/*filepath") {
+		t.Errorf("removeRoute should have succeeded for '/files/*filepath'")
+	}
+	checkRequests(t, tree, testRequests{
+		{"/files/a.txt", true, "", nil},
+	})
+
+	// removing it again now fails
+	if tree.removeRoute("/files/*filepath") {
+		t.Errorf("removeRoute should fail when the route no longer exists")
+	}
+
+	checkRequests(t, tree, testRequests{
+		{"/hi", false, "/hi", nil},
+		{"/contact", false, "/contact", nil},
+		{"/co", false, "/co", nil},
+		{"/con", false, "/con", nil},
+	})
+}
+
 func TestTreeWildcard(t *testing.T) {
 	tree := &node{}
 
@@ -296,6 +446,43 @@ func TestTreeChildConflict(t *testing.T) {
 	testRoutes(t, routes)
 }
 
+// TestTreeStaticAndParamAtSameSegmentAlwaysConflict pins down getValue's
+// documented precedence: a static child and a param child can never
+// coexist at the same tree segment, so "/users/new" and "/users/:id"
+// always panic at registration, in either order -- there's no runtime
+// "static beats param" tie-break to fall back on.
+func TestTreeStaticAndParamAtSameSegmentAlwaysConflict(t *testing.T) {
+	paramFirst := &node{}
+	paramFirst.addRoute("/users/:id", fakeHandler("/users/:id"))
+	if recv := catchPanic(func() {
+		paramFirst.addRoute("/users/new", fakeHandler("/users/new"))
+	}); recv == nil {
+		t.Fatal("expected addRoute(\"/users/new\") after \"/users/:id\" to panic")
+	}
+
+	staticFirst := &node{}
+	staticFirst.addRoute("/users/new", fakeHandler("/users/new"))
+	if recv := catchPanic(func() {
+		staticFirst.addRoute("/users/:id", fakeHandler("/users/:id"))
+	}); recv == nil {
+		t.Fatal("expected addRoute(\"/users/:id\") after \"/users/new\" to panic")
+	}
+}
+
+// TestTreeStaticSiblingOfParamNeedsDistinctPrefix confirms the documented
+// workaround still works: giving the static route its own prefix instead
+// of sharing "/users/" with the wildcard avoids the conflict entirely.
+func TestTreeStaticSiblingOfParamNeedsDistinctPrefix(t *testing.T) {
+	tree := &node{}
+	tree.addRoute("/users/:id", fakeHandler("/users/:id"))
+	tree.addRoute("/admin/users/new", fakeHandler("/admin/users/new"))
+
+	checkRequests(t, tree, testRequests{
+		{"/admin/users/new", false, "/admin/users/new", nil},
+		{"/users/42", false, "/users/:id", Params{Param{Key: "id", Value: "42"}}},
+	})
+}
+
 func TestTreeDupliatePath(t *testing.T) {
 	tree := &node{}
 
@@ -454,7 +641,7 @@ func TestTreeTrailingSlashRedirect(t *testing.T) {
 		"/doc/",
 	}
 	for _, route := range tsrRoutes {
-		handler, _, tsr := tree.getValue(route, nil, false)
+		handler, _, tsr, _ := tree.getValue(route, nil, false)
 		if handler != nil {
 			t.Fatalf("non-nil handler for TSR route '%s", route)
 		} else if !tsr {
@@ -471,7 +658,7 @@ func TestTreeTrailingSlashRedirect(t *testing.T) {
 		"/api/world/abc",
 	}
 	for _, route := range noTsrRoutes {
-		handler, _, tsr := tree.getValue(route, nil, false)
+		handler, _, tsr, _ := tree.getValue(route, nil, false)
 		if handler != nil {
 			t.Fatalf("non-nil handler for No-TSR route '%s", route)
 		} else if tsr {
@@ -490,7 +677,7 @@ func TestTreeRootTrailingSlashRedirect(t *testing.T) {
 		t.Fatalf("panic inserting test route: %v", recv)
 	}
 
-	handler, _, tsr := tree.getValue("/", nil, false)
+	handler, _, tsr, _ := tree.getValue("/", nil, false)
 	if handler != nil {
 		t.Fatalf("non-nil handler")
 	} else if tsr {
@@ -630,8 +817,6 @@ func TestTreeFindCaseInsensitivePath(t *testing.T) {
 }
 
 func TestTreeInvalidNodeType(t *testing.T) {
-	const panicMsg = "invalid node type"
-
 	tree := &node{}
 	tree.addRoute("/", fakeHandler("/"))
 	tree.addRoute("/:page", fakeHandler("/:page"))
@@ -639,20 +824,16 @@ func TestTreeInvalidNodeType(t *testing.T) {
 	// set invalid node type
 	tree.children[0].nType = 42
 
-	// normal lookup
-	recv := catchPanic(func() {
-		tree.getValue("/test", nil, false)
-	})
-	if rs, ok := recv.(string); !ok || rs != panicMsg {
-		t.Fatalf("Expected panic '"+panicMsg+"', got '%v'", recv)
+	// normal lookup: a corrupted node type is treated as a miss (-> 404)
+	// rather than panicking the request goroutine.
+	handlers, _, _, _ := tree.getValue("/test", nil, false)
+	if handlers != nil {
+		t.Fatalf("Expected no match for corrupted node type, got handlers %v", handlers)
 	}
 
-	// case-insensitive lookup
-	recv = catchPanic(func() {
-		tree.findCaseInsensitivePath("/test", true)
-	})
-	if rs, ok := recv.(string); !ok || rs != panicMsg {
-		t.Fatalf("Expected panic '"+panicMsg+"', got '%v'", recv)
+	// case-insensitive lookup: same resilience policy.
+	if _, found := tree.findCaseInsensitivePath("/test", true); found {
+		t.Fatalf("Expected no match for corrupted node type, got found=true")
 	}
 }
 
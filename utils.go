@@ -11,6 +11,8 @@ import (
 	"path"
 	"reflect"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -49,6 +51,29 @@ func WrapH(h http.Handler) HandlerFunc {
 	}
 }
 
+// WrapMiddleware adapts a standard func(http.Handler) http.Handler middleware
+// (the shape used by gorilla/mux, chi, negroni, ...) into a Gin middleware.
+// The wrapped middleware's calls to its "next" handler resume the Gin chain
+// via c.Next(), so downstream Gin handlers still run; if it returns without
+// calling next, the chain is aborted so the rest is skipped too, the same as
+// calling c.Abort() directly would skip it. Since mw is handed c.Writer/
+// c.Request directly, status and bytes-written accounting works the same as
+// it does for WrapH and WrapF.
+func WrapMiddleware(mw func(http.Handler) http.Handler) HandlerFunc {
+	return func(c *Context) {
+		calledNext := false
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calledNext = true
+			c.Request = r
+			c.Next()
+		})
+		mw(next).ServeHTTP(c.Writer, c.Request)
+		if !calledNext {
+			c.Abort()
+		}
+	}
+}
+
 // H is a shortcut for map[string]interface{}
 type H map[string]interface{}
 
@@ -99,15 +124,45 @@ func chooseData(custom, wildcard interface{}) interface{} {
 	return custom
 }
 
+// parseAccept splits an Accept header into its offered MIME types, ordered
+// by descending q-value (ties keep the header's original relative order) so
+// Context.NegotiateFormat's linear scan picks the client's actual
+// preference, e.g. "application/json;q=0.9, text/xml" yields
+// ["text/xml", "application/json"]. Types with no q parameter default to 1.0
+// per RFC 7231, and a malformed q value is treated the same as absent
+// rather than rejecting the whole header.
 func parseAccept(acceptHeader string) []string {
 	parts := strings.Split(acceptHeader, ",")
-	out := make([]string, 0, len(parts))
+	type weighted struct {
+		mime string
+		q    float64
+	}
+	out := make([]weighted, 0, len(parts))
 	for _, part := range parts {
-		if part = strings.TrimSpace(strings.Split(part, ";")[0]); part != "" {
-			out = append(out, part)
+		params := strings.Split(part, ";")
+		mime := strings.TrimSpace(params[0])
+		if mime == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if value := strings.TrimPrefix(param, "q="); value != param {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
 		}
+		out = append(out, weighted{mime: mime, q: q})
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].q > out[j].q })
+
+	mimes := make([]string, len(out))
+	for i, w := range out {
+		mimes[i] = w.mime
 	}
-	return out
+	return mimes
 }
 
 func lastChar(str string) uint8 {
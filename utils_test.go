@@ -48,6 +48,48 @@ func TestWrap(t *testing.T) {
 	assert.Equal(t, "hola!", w.Body.String())
 }
 
+func TestWrapMiddleware(t *testing.T) {
+	router := New()
+	var calledBefore, calledAfter bool
+	logMiddleware := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			calledBefore = true
+			next.ServeHTTP(w, req)
+			calledAfter = true
+		})
+	}
+
+	router.Use(WrapMiddleware(logMiddleware))
+	router.GET("/path", func(c *Context) {
+		c.String(http.StatusOK, "hola!")
+	})
+
+	w := performRequest(router, "GET", "/path")
+	assert.True(t, calledBefore)
+	assert.True(t, calledAfter)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hola!", w.Body.String())
+}
+
+func TestWrapMiddlewareShortCircuits(t *testing.T) {
+	router := New()
+	var calledHandler bool
+	denyMiddleware := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+	}
+
+	router.Use(WrapMiddleware(denyMiddleware))
+	router.GET("/path", func(c *Context) {
+		calledHandler = true
+	})
+
+	w := performRequest(router, "GET", "/path")
+	assert.False(t, calledHandler)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
 func TestLastChar(t *testing.T) {
 	assert.Equal(t, uint8('a'), lastChar("hola"))
 	assert.Equal(t, uint8('s'), lastChar("adios"))
@@ -63,6 +105,16 @@ func TestParseAccept(t *testing.T) {
 	assert.Equal(t, "*/*", parts[3])
 }
 
+func TestParseAcceptReordersByQValue(t *testing.T) {
+	parts := parseAccept("application/json;q=0.9, text/xml")
+	assert.Equal(t, []string{"text/xml", "application/json"}, parts)
+}
+
+func TestParseAcceptMalformedQValueDefaultsToOne(t *testing.T) {
+	parts := parseAccept("application/json;q=notanumber, text/xml;q=0.5")
+	assert.Equal(t, []string{"application/json", "text/xml"}, parts)
+}
+
 func TestChooseData(t *testing.T) {
 	A := "a"
 	B := "b"